@@ -2,6 +2,7 @@ package user
 
 import (
 	"context"
+	"errors"
 	"giga"
 	"log"
 	"net/http"
@@ -13,32 +14,56 @@ import (
 type HandlerUser struct {
 }
 
+// RegisterRequest 用户注册参数
+type RegisterRequest struct {
+	Username string `form:"username" json:"username" binding:"required,min=3,max=32"`
+	Password string `form:"password" json:"password" binding:"required,min=6,max=64"`
+	Age      int    `form:"age" json:"age" binding:"required,min=1,max=150"`
+	Mobile   string `form:"mobile" json:"mobile" binding:"required,mobile"`
+}
+
 func (h *HandlerUser) UserRegister(c *giga.Context) {
+	// 绑定并校验参数，失败时已返回400
+	var req RegisterRequest
+	if err := c.Bind(&req); err != nil {
+		return
+	}
 	c.JSON(http.StatusOK, giga.H{
-		"username": c.PostForm("username"),
-		"password": c.PostForm("password"),
-		"age":      c.PostForm("age"),
-		"mobile":   c.PostForm("mobile"),
+		"username": req.Username,
+		"password": req.Password,
+		"age":      req.Age,
+		"mobile":   req.Mobile,
 	})
 }
 
 func (h *HandlerUser) UserLogin(c *giga.Context) {
 	// 取出参数
 	mobile := c.PostForm("mobile")
-	// Context.Key中取出服务实例
-	userService, ok := c.Keys["user"].(pb.UserServiceClient)
+	// Context.Keys中取出服务实例
+	userService, ok := giga.GetValue[pb.UserServiceClient](c, "user")
 	if !ok {
-		log.Fatalf("could not get rpc client")
+		log.Printf("could not get rpc client")
+		c.Fail(http.StatusInternalServerError, "user service unavailable")
 		return
 	}
-	// 设置超时控制
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	// 设置超时控制，以c为父context，客户端断开连接时同时取消rpc调用
+	ctx, cancel := context.WithTimeout(c, 5*time.Second)
 	defer cancel()
 	// 执行RPC调用并打印收到的响应数据
 	res, err := userService.GetCaptcha(ctx, &pb.GetCaptchaRequest{Mobile: mobile})
 	if err != nil {
-		log.Fatalf("could not get captcha: %v", err)
+		// 客户端已断开连接或服务正在关闭，无需返回响应
+		if c.Err() != nil || errors.Is(err, context.Canceled) {
+			return
+		}
+		log.Printf("could not get captcha: %v", err)
+		c.Fail(http.StatusBadGateway, "get captcha failed")
 		return
 	}
-	c.JSON(http.StatusOK, giga.H{"code": res.Code})
+	// 移动端通过 Accept: application/x-protobuf 直接获取pb消息，其余返回json
+	c.Negotiate(http.StatusOK, giga.Negotiate{
+		Offered:      []string{giga.MIMEJSON, giga.MIMEProtoBuf},
+		JSONData:     giga.H{"code": res.Code},
+		ProtoBufData: res,
+	})
 }
@@ -7,10 +7,9 @@ import (
 // MiddlewareRpc 将rpc client实例存在Keys中
 func MiddlewareRpc(services map[string]interface{}) giga.HandlerFunc {
 	return func(c *giga.Context) {
-		// 将rpc client实例存在Keys中
-		c.Keys = make(map[string]interface{})
+		// 使用Set保存，不会覆盖之前中间件保存的值
 		for k, v := range services {
-			c.Keys[k] = v
+			c.Set(k, v)
 		}
 		c.Next()
 	}
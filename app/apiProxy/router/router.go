@@ -5,6 +5,7 @@ import (
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
 	"log"
+	"net/http"
 
 	"giga"
 
@@ -35,6 +36,14 @@ func InitRouter(r *giga.Engine) {
 	register := NewRegister(r)
 	register.AddRoute(&RouterGreet{})
 	register.AddRoute(&RouterUser{})
+
+	// 404及405统一使用json格式的错误响应
+	r.NoRoute(func(c *giga.Context) {
+		c.Fail(http.StatusNotFound, "route not found")
+	})
+	r.NoMethod(func(c *giga.Context) {
+		c.Fail(http.StatusMethodNotAllowed, "method not allowed")
+	})
 }
 
 func InitRpcClient() {
@@ -0,0 +1,224 @@
+package giga
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/textproto"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// 请求数据绑定到结构体，按来源使用不同的tag：
+// json 请求体为json时使用，form 表单及查询参数，uri 路由参数，header 请求头，
+// 绑定完成后按binding tag校验，例如
+//
+//	type RegisterRequest struct {
+//		Mobile string `form:"mobile" json:"mobile" binding:"required,mobile"`
+//		Age    int    `form:"age" json:"age" binding:"min=1,max=150"`
+//	}
+
+// Bind 按请求方法及Content-Type自动选择解析方式绑定并校验，失败时写入400(请求体过大时为413)响应并中断处理链，
+// 校验失败的响应格式为 {"message": "...", "errors": [{"field": "age", "rule": "max", ...}]}
+func (c *Context) Bind(obj interface{}) error {
+	err := c.ShouldBind(obj)
+	if err == nil {
+		return nil
+	}
+	var fieldErrors ValidationErrors
+	if errors.As(err, &fieldErrors) {
+		c.AbortWithStatusJSON(http.StatusBadRequest, H{"message": fieldErrors.Error(), "errors": fieldErrors})
+	} else if !c.abortIfTooLarge(err) {
+		c.AbortWithStatusJSON(http.StatusBadRequest, H{"message": err.Error()})
+	}
+	return err
+}
+
+// ShouldBind 按请求方法及Content-Type自动选择解析方式绑定并校验，失败时只返回error，由调用方处理：
+// GET、HEAD、DELETE 请求绑定查询参数，application/json 绑定json，其余按表单绑定(包含查询参数)
+func (c *Context) ShouldBind(obj interface{}) error {
+	if c.Method == "GET" || c.Method == "HEAD" || c.Method == "DELETE" {
+		return c.ShouldBindQuery(obj)
+	}
+	contentType, _, _ := mime.ParseMediaType(c.Req.Header.Get("Content-Type"))
+	if contentType == "application/json" {
+		return c.ShouldBindJSON(obj)
+	}
+	return c.ShouldBindForm(obj)
+}
+
+// ShouldBindJSON 将json请求体绑定到obj并校验
+func (c *Context) ShouldBindJSON(obj interface{}) error {
+	if c.Req.Body == nil {
+		return errors.New("giga: empty request body")
+	}
+	if err := json.NewDecoder(c.Req.Body).Decode(obj); err != nil {
+		if err == io.EOF {
+			return errors.New("giga: empty request body")
+		}
+		return fmt.Errorf("giga: invalid json body: %w", err)
+	}
+	return validate(obj)
+}
+
+// ShouldBindForm 将表单及查询参数绑定到obj并校验，使用form tag
+func (c *Context) ShouldBindForm(obj interface{}) error {
+	if err := c.parseMultipartForm(); err != nil {
+		return fmt.Errorf("giga: invalid form body: %w", err)
+	}
+	return bindValues(obj, c.Req.Form, "form")
+}
+
+// ShouldBindQuery 将查询参数绑定到obj并校验，使用form tag
+func (c *Context) ShouldBindQuery(obj interface{}) error {
+	return bindValues(obj, c.Req.URL.Query(), "form")
+}
+
+// ShouldBindUri 将路由参数绑定到obj并校验，使用uri tag，例如 /user/:id 对应 `uri:"id"`
+func (c *Context) ShouldBindUri(obj interface{}) error {
+	values := make(map[string][]string, len(c.Params))
+	for _, p := range c.Params {
+		values[p.Key] = []string{p.Value}
+	}
+	return bindValues(obj, values, "uri")
+}
+
+// ShouldBindHeader 将请求头绑定到obj并校验，使用header tag，请求头名称不区分大小写
+func (c *Context) ShouldBindHeader(obj interface{}) error {
+	return bindValues(obj, c.Req.Header, "header")
+}
+
+func bindValues(obj interface{}, values map[string][]string, tag string) error {
+	v := reflect.ValueOf(obj)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return errors.New("giga: binding target must be a non-nil pointer to struct")
+	}
+	var fieldErrors ValidationErrors
+	mapStruct(v.Elem(), values, tag, &fieldErrors)
+	if len(fieldErrors) > 0 {
+		return fieldErrors
+	}
+	return validate(obj)
+}
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	durationType = reflect.TypeOf(time.Duration(0))
+)
+
+// mapStruct 按tag将values中的值写入结构体的各个字段，未设置tag的嵌套结构体递归处理
+func mapStruct(v reflect.Value, values map[string][]string, tag string, fieldErrors *ValidationErrors) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name := field.Tag.Get(tag)
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			if field.Type.Kind() == reflect.Struct && field.Type != timeType {
+				mapStruct(v.Field(i), values, tag, fieldErrors)
+				continue
+			}
+			name = field.Name
+		}
+		if tag == "header" {
+			name = textproto.CanonicalMIMEHeaderKey(name)
+		}
+
+		vals, ok := values[name]
+		if !ok || len(vals) == 0 {
+			continue
+		}
+		if err := setField(v.Field(i), vals); err != nil {
+			*fieldErrors = append(*fieldErrors, FieldError{
+				Field:   name,
+				Rule:    "type",
+				Param:   field.Type.String(),
+				Message: fmt.Sprintf("%s must be a valid %s", name, field.Type),
+			})
+		}
+	}
+}
+
+func setField(v reflect.Value, vals []string) error {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return setField(v.Elem(), vals)
+	case reflect.Slice:
+		slice := reflect.MakeSlice(v.Type(), len(vals), len(vals))
+		for i, val := range vals {
+			if err := setValue(slice.Index(i), val); err != nil {
+				return err
+			}
+		}
+		v.Set(slice)
+		return nil
+	}
+	return setValue(v, vals[0])
+}
+
+func setValue(v reflect.Value, val string) error {
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(val)
+	case reflect.Bool:
+		// 复选框提交的值为on
+		b, err := strconv.ParseBool(val)
+		if val == "on" {
+			b, err = true, nil
+		}
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if v.Type() == durationType {
+			d, err := time.ParseDuration(val)
+			if err != nil {
+				return err
+			}
+			v.SetInt(int64(d))
+			return nil
+		}
+		n, err := strconv.ParseInt(val, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(val, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(val, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case reflect.Struct:
+		if v.Type() != timeType {
+			return fmt.Errorf("unsupported type %s", v.Type())
+		}
+		t, err := time.Parse(time.RFC3339, val)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(t))
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package giga
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type registerForm struct {
+	Username string   `form:"username" json:"username" binding:"required,min=3,max=16"`
+	Password string   `form:"password" json:"password" binding:"required,min=6"`
+	Age      int      `form:"age" json:"age" binding:"required,min=1,max=150"`
+	Mobile   string   `form:"mobile" json:"mobile" binding:"required,mobile"`
+	Email    string   `form:"email" json:"email" binding:"omitempty,email"`
+	Gender   string   `form:"gender" json:"gender" binding:"oneof=male female"`
+	Tags     []string `form:"tag" json:"tags" binding:"max=2"`
+	Code     string   `form:"code" json:"code" binding:"omitempty,regex=^[a-z]{2,4}$"`
+}
+
+func TestShouldBind(t *testing.T) {
+	body := "username=giga&password=123456&age=18&mobile=13800138000&tag=a&tag=b&gender=male"
+	req := httptest.NewRequest("POST", "/register?code=ab", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	c := newTestContext(NewEngine(), httptest.NewRecorder(), req)
+
+	var form registerForm
+	if err := c.ShouldBind(&form); err != nil {
+		t.Fatalf("bind form failed: %v", err)
+	}
+	if form.Username != "giga" || form.Age != 18 || len(form.Tags) != 2 || form.Code != "ab" {
+		t.Fatalf("unexpected form %+v", form)
+	}
+
+	req = httptest.NewRequest("POST", "/register", strings.NewReader(
+		`{"username":"gi","password":"123456","age":200,"mobile":"123","email":"a@b","gender":"x"}`))
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	c = newTestContext(NewEngine(), httptest.NewRecorder(), req)
+	form = registerForm{}
+	err := c.ShouldBind(&form)
+	fieldErrors, ok := err.(ValidationErrors)
+	if !ok {
+		t.Fatalf("expect ValidationErrors, got %v", err)
+	}
+	rules := make([]string, 0)
+	for _, e := range fieldErrors {
+		rules = append(rules, e.Field+":"+e.Rule)
+	}
+	if strings.Join(rules, ",") != "username:min,age:max,mobile:mobile,email:email,gender:oneof" {
+		t.Fatalf("unexpected errors %v", rules)
+	}
+}
+
+func TestValidateZeroValues(t *testing.T) {
+	var form struct {
+		Age    int    `form:"age" binding:"min=1"`
+		Level  int    `form:"level" binding:"oneof=1 2"`
+		Gender string `form:"gender" binding:"oneof=a b"`
+		Score  *int   `form:"score" binding:"min=60"`
+		Nick   string `form:"nick" binding:"omitempty,min=2"`
+	}
+	// 零值同样需要满足min、oneof等规则，omitempty时跳过
+	err := bindValues(&form, map[string][]string{"age": {"0"}, "level": {"0"}}, "form")
+	fieldErrors, ok := err.(ValidationErrors)
+	if !ok {
+		t.Fatalf("expect ValidationErrors, got %v", err)
+	}
+	rules := make([]string, 0)
+	for _, e := range fieldErrors {
+		rules = append(rules, e.Field+":"+e.Rule)
+	}
+	if strings.Join(rules, ",") != "age:min,level:oneof,gender:oneof,score:min" {
+		t.Fatalf("unexpected errors %v", rules)
+	}
+}
+
+func TestShouldBindUriAndHeader(t *testing.T) {
+	var uri struct {
+		ID int64 `uri:"id" binding:"required"`
+	}
+	var header struct {
+		Token   string `header:"x-token" binding:"required"`
+		Version *int   `header:"Accept-Version"`
+	}
+
+	r := NewEngine()
+	r.GET("/user/:id", func(c *Context) {
+		if err := c.ShouldBindUri(&uri); err != nil {
+			c.Fail(http.StatusBadRequest, err.Error())
+			return
+		}
+		if err := c.ShouldBindHeader(&header); err != nil {
+			c.Fail(http.StatusBadRequest, err.Error())
+			return
+		}
+	})
+
+	req := httptest.NewRequest("GET", "/user/42", nil)
+	req.Header.Set("X-Token", "secret")
+	req.Header.Set("Accept-Version", "2")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || uri.ID != 42 || header.Token != "secret" || *header.Version != 2 {
+		t.Fatalf("unexpected result %d %s, %+v %+v", w.Code, w.Body.String(), uri, header)
+	}
+
+	if w = performRequest(r, "GET", "/user/abc"); w.Code != http.StatusBadRequest {
+		t.Fatalf("invalid uri param should fail, got %d", w.Code)
+	}
+}
+
+func TestBindRendersErrors(t *testing.T) {
+	r := NewEngine()
+	r.POST("/register", func(c *Context) {
+		var form registerForm
+		if c.Bind(&form) != nil {
+			return
+		}
+		c.String(http.StatusOK, "ok")
+	})
+
+	req := httptest.NewRequest("POST", "/register", strings.NewReader("age=abc"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expect 400, got %d", w.Code)
+	}
+	var resp struct {
+		Message string       `json:"message"`
+		Errors  []FieldError `json:"errors"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || len(resp.Errors) != 1 || resp.Errors[0].Rule != "type" {
+		t.Fatalf("unexpected response %s", w.Body.String())
+	}
+}
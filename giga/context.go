@@ -1,40 +1,127 @@
 package giga
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"math"
 	"net/http"
+	"strconv"
+	"sync"
+	"time"
 )
 
 type H map[string]interface{}
 
+// Param 路由参数，例如 /user/:id 中的 id
+type Param struct {
+	Key   string
+	Value string
+}
+
+// Params 路由参数列表，按在路径中出现的顺序排列
+type Params []Param
+
+// Get 返回参数值以及参数是否存在
+func (ps Params) Get(name string) (string, bool) {
+	for _, p := range ps {
+		if p.Key == name {
+			return p.Value, true
+		}
+	}
+	return "", false
+}
+
+// ByName 返回参数值，参数不存在时返回空字符串
+func (ps Params) ByName(name string) string {
+	value, _ := ps.Get(name)
+	return value
+}
+
 type Context struct {
-	// 基础的输入输出，标准库提供
-	Writer http.ResponseWriter
-	Req    *http.Request
+	// 基础的输入输出，Writer封装了标准库的http.ResponseWriter，记录状态码及写入的字节数
+	Writer    ResponseWriter
+	writermem responseWriter
+	Req       *http.Request
 	// 从req提取的参数
 	Path   string
 	Method string
-	Params map[string]string
+	// 路由参数，底层切片在请求结束后会被复用，请求结束后继续使用需调用Copy
+	Params Params
+	// host中的参数，例如 :tenant.example.com 中的 tenant
+	HostParams Params
 
-	Keys map[string]interface{}
+	// Keys 请求范围内的键值对，通过Set、Get等方法读写，可在处理函数启动的goroutine中并发使用
+	Keys   map[string]interface{}
+	keysMu sync.RWMutex
 	// middleware
 	handlers []HandlerFunc
 	index    int
 	// 返回
 	StatusCode int
+
+	engine *Engine
+}
+
+// reset 清空上一个请求的状态，Context由Engine通过sync.Pool复用
+func (c *Context) reset(w http.ResponseWriter, req *http.Request) {
+	c.writermem.reset(w)
+	c.Writer = &c.writermem
+	c.Req = req
+	c.Path = req.URL.Path
+	c.Method = req.Method
+	c.Params = nil
+	c.HostParams = nil
+	// 不复用map，防止之前请求中通过Copy等仍持有Keys的goroutine读到新请求的值
+	c.Keys = nil
+	c.handlers = nil
+	c.index = -1
+	c.StatusCode = 0
 }
 
-func newContext(w http.ResponseWriter, req *http.Request) *Context {
-	return &Context{
-		Writer: w,
-		Req:    req,
-		Path:   req.URL.Path,
-		Method: req.Method,
-		index:  -1,
+// release 请求结束后放回池中前，释放对请求、响应及处理链的引用
+func (c *Context) release() {
+	c.writermem.ResponseWriter = nil
+	c.Writer = nil
+	c.Req = nil
+	c.Params = nil
+	c.HostParams = nil
+	c.Keys = nil
+	c.handlers = nil
+}
+
+// Copy 返回Context的副本，用于在处理函数返回后仍需使用Context的goroutine中，
+// 副本不能调用Next，写入响应时返回ErrCopiedContext，Params、HostParams及Keys为独立的拷贝。
+// 副本的Done在原请求结束后关闭，后台任务需要独立的超时时可使用 context.WithoutCancel(c.Copy())
+func (c *Context) Copy() *Context {
+	cp := &Context{
+		Req:        c.Req,
+		Path:       c.Path,
+		Method:     c.Method,
+		Params:     append(Params(nil), c.Params...),
+		HostParams: append(Params(nil), c.HostParams...),
+		index:      abortIndex,
+		StatusCode: c.StatusCode,
+		engine:     c.engine,
 	}
+	// 副本的Writer不关联原响应
+	cp.writermem.reset(&copiedResponseWriter{header: make(http.Header)})
+	cp.Writer = &cp.writermem
+
+	c.keysMu.RLock()
+	if c.Keys != nil {
+		cp.Keys = make(map[string]interface{}, len(c.Keys))
+		for k, v := range c.Keys {
+			cp.Keys[k] = v
+		}
+	}
+	c.keysMu.RUnlock()
+	return cp
 }
 
+// abortIndex 中断后index的取值，远大于处理链的长度，保证Next不会再执行任何处理函数
+const abortIndex = math.MaxInt >> 1
+
 func (c *Context) Next() {
 	c.index++
 	for ; c.index < len(c.handlers); c.index++ {
@@ -42,12 +129,76 @@ func (c *Context) Next() {
 	}
 }
 
+// Abort 中断处理链，当前处理函数返回后，之后的中间件及请求处理函数都不会执行，
+// 已执行的中间件在c.Next()返回后可通过IsAborted得知处理链被中断
+func (c *Context) Abort() {
+	c.index = abortIndex
+}
+
+// IsAborted 处理链是否已被中断
+func (c *Context) IsAborted() bool {
+	return c.index >= abortIndex
+}
+
+// AbortWithStatus 写入状态码并中断处理链，例如鉴权失败时 c.AbortWithStatus(http.StatusUnauthorized)
+func (c *Context) AbortWithStatus(code int) {
+	c.Status(code)
+	c.Abort()
+}
+
+// AbortWithStatusJSON 以json格式写入响应并中断处理链
+func (c *Context) AbortWithStatusJSON(code int, obj interface{}) {
+	c.Abort()
+	c.JSON(code, obj)
+}
+
+// Fail 以 {"message": err} 格式写入响应并中断处理链
 func (c *Context) Fail(code int, err string) {
-	c.index = len(c.handlers)
-	c.JSON(code, H{"message": err})
+	c.AbortWithStatusJSON(code, H{"message": err})
+}
+
+// Context 实现了context.Context，Deadline、Done、Err 使用请求的context，
+// 客户端断开连接或服务关闭超时后Done会被关闭，可直接传入rpc调用，例如
+//
+//	res, err := userService.GetCaptcha(c, &pb.GetCaptchaRequest{Mobile: mobile})
+var _ context.Context = (*Context)(nil)
+
+func (c *Context) Deadline() (deadline time.Time, ok bool) {
+	if c.Req == nil {
+		return
+	}
+	return c.Req.Context().Deadline()
+}
+
+func (c *Context) Done() <-chan struct{} {
+	if c.Req == nil {
+		return nil
+	}
+	return c.Req.Context().Done()
+}
+
+func (c *Context) Err() error {
+	if c.Req == nil {
+		return nil
+	}
+	return c.Req.Context().Err()
+}
+
+// Value key为string且在Keys中存在时返回Keys中的值，否则返回请求context中的值
+func (c *Context) Value(key interface{}) interface{} {
+	if k, ok := key.(string); ok {
+		if value, exists := c.Get(k); exists {
+			return value
+		}
+	}
+	if c.Req == nil {
+		return nil
+	}
+	return c.Req.Context().Value(key)
 }
 
 func (c *Context) PostForm(key string) string {
+	c.parseMultipartForm()
 	return c.Req.FormValue(key)
 }
 
@@ -56,10 +207,50 @@ func (c *Context) Query(key string) string {
 }
 
 func (c *Context) Param(key string) string {
-	value, _ := c.Params[key]
-	return value
+	return c.Params.ByName(key)
 }
 
+// HostParam 返回host中的参数，例如 r.Host(":tenant.example.com") 中的 tenant
+func (c *Context) HostParam(key string) string {
+	return c.HostParams.ByName(key)
+}
+
+// ParamInt 将路由参数解析为int，参数不存在或格式错误时返回error
+func (c *Context) ParamInt(key string) (int, error) {
+	value, err := c.param(key)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(value)
+}
+
+// ParamInt64 将路由参数解析为int64，参数不存在或格式错误时返回error
+func (c *Context) ParamInt64(key string) (int64, error) {
+	value, err := c.param(key)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(value, 10, 64)
+}
+
+// ParamFloat64 将路由参数解析为float64，参数不存在或格式错误时返回error
+func (c *Context) ParamFloat64(key string) (float64, error) {
+	value, err := c.param(key)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseFloat(value, 64)
+}
+
+func (c *Context) param(key string) (string, error) {
+	value, ok := c.Params.Get(key)
+	if !ok {
+		return "", fmt.Errorf("giga: param %s not found", key)
+	}
+	return value, nil
+}
+
+// Status 设置响应的状态码，状态码在写入响应体时才真正写入，写入后再次设置会被忽略
 func (c *Context) Status(code int) {
 	c.StatusCode = code
 	c.Writer.WriteHeader(code)
@@ -70,7 +261,6 @@ func (c *Context) SetHeader(key string, value string) {
 }
 
 func (c *Context) String(code int, format string, values ...interface{}) {
-	// 注意此处，只能先使用Writer.Header().Set(),再使用Writer.WriteHeader()
 	c.SetHeader("Content-Type", "text/plain")
 	c.Status(code)
 	c.Writer.Write([]byte(fmt.Sprintf(format, values...)))
@@ -89,9 +279,3 @@ func (c *Context) Data(code int, data []byte) {
 	c.Status(code)
 	c.Writer.Write(data)
 }
-
-func (c *Context) HTML(code int, html string) {
-	c.SetHeader("Content-Type", "text/html")
-	c.Status(code)
-	c.Writer.Write([]byte(html))
-}
@@ -0,0 +1,389 @@
+package giga
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAbort(t *testing.T) {
+	r := NewEngine()
+	var trace []string
+	r.Use(func(c *Context) {
+		trace = append(trace, "logger")
+		c.Next()
+		if c.IsAborted() {
+			trace = append(trace, "aborted")
+		}
+	})
+	auth := func(c *Context) {
+		trace = append(trace, "auth")
+		if c.Query("token") == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, H{"message": "unauthorized"})
+			// 中断后继续调用Next也不会执行之后的处理函数
+			c.Next()
+			return
+		}
+		c.Next()
+	}
+	r.GET("/user", auth, func(c *Context) {
+		trace = append(trace, "handler")
+		c.AbortWithStatus(http.StatusNoContent)
+	})
+
+	w := performRequest(r, "GET", "/user")
+	if w.Code != http.StatusUnauthorized || strings.Join(trace, ",") != "logger,auth,aborted" {
+		t.Fatalf("unexpected code %d, trace %v", w.Code, trace)
+	}
+
+	trace = nil
+	w = performRequest(r, "GET", "/user?token=1")
+	if w.Code != http.StatusNoContent || strings.Join(trace, ",") != "logger,auth,handler,aborted" {
+		t.Fatalf("unexpected code %d, trace %v", w.Code, trace)
+	}
+}
+
+func TestCookie(t *testing.T) {
+	r := NewEngine()
+	r.SecretKey = []byte("giga-secret")
+	r.GET("/login", func(c *Context) {
+		c.SetCookie("lang", "zh cn", CookieOptions{MaxAge: 60, HttpOnly: true, Secure: true, SameSite: http.SameSiteLaxMode})
+		c.SetSignedCookie("uid", "42")
+		c.SetEncryptedCookie("session", "secret-session")
+		c.Redirect(http.StatusFound, "/home")
+	})
+	r.GET("/home", func(c *Context) {
+		lang, _ := c.Cookie("lang")
+		uid, err := c.SignedCookie("uid")
+		if err != nil {
+			c.Fail(http.StatusUnauthorized, err.Error())
+			return
+		}
+		session, err := c.EncryptedCookie("session")
+		if err != nil {
+			c.Fail(http.StatusUnauthorized, err.Error())
+			return
+		}
+		c.String(http.StatusOK, "%s %s %s", lang, uid, session)
+	})
+
+	w := performRequest(r, "GET", "/login")
+	if w.Code != http.StatusFound || w.Header().Get("Location") != "/home" {
+		t.Fatalf("expect redirect to /home, got %d %q", w.Code, w.Header().Get("Location"))
+	}
+	cookies := w.Result().Cookies()
+	if len(cookies) != 3 || !cookies[0].HttpOnly || !cookies[0].Secure || cookies[0].SameSite != http.SameSiteLaxMode {
+		t.Fatalf("unexpected cookies %v", cookies)
+	}
+	if strings.Contains(cookies[2].Value, "secret-session") {
+		t.Fatal("encrypted cookie should not contain plain value")
+	}
+
+	req := httptest.NewRequest("GET", "/home", nil)
+	for _, cookie := range cookies {
+		req.AddCookie(cookie)
+	}
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Body.String() != "zh cn 42 secret-session" {
+		t.Fatalf("unexpected response %d %q", w.Code, w.Body.String())
+	}
+
+	// 篡改签名cookie
+	req = httptest.NewRequest("GET", "/home", nil)
+	cookies[1].Value = "NDM" + cookies[1].Value[3:]
+	for _, cookie := range cookies {
+		req.AddCookie(cookie)
+	}
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("tampered cookie should be rejected, got %d", w.Code)
+	}
+}
+
+func TestRedirectPOST(t *testing.T) {
+	r := NewEngine()
+	var written bool
+	r.POST("/login", func(c *Context) {
+		c.Redirect(http.StatusSeeOther, "/home")
+		written = c.Writer.Written()
+		// 重定向后状态码不能再被修改
+		c.Status(http.StatusOK)
+	})
+
+	w := performRequest(r, "POST", "/login")
+	if !written || w.Code != http.StatusSeeOther || w.Header().Get("Location") != "/home" {
+		t.Fatalf("expect written redirect to /home, got %v %d %q", written, w.Code, w.Header().Get("Location"))
+	}
+}
+
+func TestRedirectInvalidCode(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("redirect with 200 should panic")
+		}
+	}()
+	c := newTestContext(NewEngine(), httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	c.Redirect(http.StatusOK, "/")
+}
+
+func TestResponseWriter(t *testing.T) {
+	r := NewEngine()
+	var status, size int
+	r.Use(func(c *Context) {
+		c.Next()
+		status, size = c.Writer.Status(), c.Writer.Size()
+	})
+	r.GET("/twice", func(c *Context) {
+		c.JSON(http.StatusCreated, H{"ok": true})
+		// 状态码已写入，再次设置被忽略
+		c.String(http.StatusInternalServerError, "done")
+	})
+	r.GET("/status", func(c *Context) {
+		c.Status(http.StatusAccepted)
+		c.Status(http.StatusNoContent)
+		if c.Writer.Written() {
+			t.Error("status should not be written before body")
+		}
+	})
+	r.NoRoute(func(c *Context) {})
+
+	w := performRequest(r, "GET", "/twice")
+	if w.Code != http.StatusCreated || status != http.StatusCreated || size != len(w.Body.String()) {
+		t.Fatalf("unexpected code %d, status %d, size %d, body %q", w.Code, status, size, w.Body.String())
+	}
+	w = performRequest(r, "GET", "/status")
+	if w.Code != http.StatusNoContent || status != http.StatusNoContent || size != 0 {
+		t.Fatalf("unexpected code %d, status %d, size %d", w.Code, status, size)
+	}
+	// NoRoute处理函数未写入响应时默认返回404
+	w = performRequest(r, "GET", "/missing")
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("unexpected code %d", w.Code)
+	}
+	// HEAD请求丢弃响应体
+	w = performRequest(r, "HEAD", "/twice")
+	if w.Code != http.StatusCreated || w.Body.Len() != 0 {
+		t.Fatalf("unexpected code %d, body %q", w.Code, w.Body.String())
+	}
+
+	// 底层支持时透传Flush，不支持Hijack时返回错误
+	rec := httptest.NewRecorder()
+	c := newTestContext(NewEngine(), rec, httptest.NewRequest("GET", "/", nil))
+	c.Writer.Flush()
+	if !rec.Flushed || !c.Writer.Written() {
+		t.Fatal("flush should be passed to the underlying writer")
+	}
+	if _, _, err := c.Writer.Hijack(); err == nil {
+		t.Fatal("hijack should fail on httptest.ResponseRecorder")
+	}
+	if err := c.Writer.Push("/app.js", nil); err != http.ErrNotSupported {
+		t.Fatalf("unexpected push error %v", err)
+	}
+}
+
+// plainResponseWriter 只实现http.ResponseWriter，不支持Flush、Hijack、Push
+type plainResponseWriter struct {
+	header http.Header
+	code   int
+	body   strings.Builder
+}
+
+func (w *plainResponseWriter) Header() http.Header         { return w.header }
+func (w *plainResponseWriter) Write(b []byte) (int, error) { return w.body.Write(b) }
+func (w *plainResponseWriter) WriteHeader(code int)        { w.code = code }
+
+func TestResponseWriterWithoutCapabilities(t *testing.T) {
+	plain := &plainResponseWriter{header: http.Header{}}
+	c := newTestContext(NewEngine(), plain, httptest.NewRequest("GET", "/", nil))
+
+	// 底层不支持Flush时，不会提前写入状态码，状态码仍可修改
+	c.Status(http.StatusAccepted)
+	c.Writer.Flush()
+	if c.Writer.Written() || plain.code != 0 {
+		t.Fatalf("flush should not write the header early, code %d", plain.code)
+	}
+	if err := c.Writer.FlushError(); !errors.Is(err, http.ErrNotSupported) {
+		t.Fatalf("unexpected flush error %v", err)
+	}
+	if err := http.NewResponseController(c.Writer).Flush(); !errors.Is(err, http.ErrNotSupported) {
+		t.Fatalf("unexpected response controller error %v", err)
+	}
+	if _, _, err := c.Writer.Hijack(); !errors.Is(err, http.ErrNotSupported) {
+		t.Fatalf("unexpected hijack error %v", err)
+	}
+	if err := c.Writer.Push("/app.js", nil); !errors.Is(err, http.ErrNotSupported) {
+		t.Fatalf("unexpected push error %v", err)
+	}
+
+	c.String(http.StatusCreated, "body")
+	if plain.code != http.StatusCreated || plain.body.String() != "body" {
+		t.Fatalf("unexpected code %d, body %q", plain.code, plain.body.String())
+	}
+}
+
+type requestIDKey struct{}
+
+func TestContextImplementsContext(t *testing.T) {
+	r := NewEngine()
+	r.Use(func(c *Context) {
+		c.Set("user", "tom")
+		c.Next()
+	})
+	var values []interface{}
+	var err error
+	r.GET("/", func(c *Context) {
+		// 以Context为父context的子context同样能取到Keys及请求context中的值
+		ctx, cancel := context.WithTimeout(c, time.Second)
+		defer cancel()
+		values = []interface{}{ctx.Value("user"), ctx.Value(requestIDKey{}), ctx.Value("missing")}
+		<-ctx.Done()
+		err = ctx.Err()
+	})
+
+	reqCtx, cancel := context.WithCancel(context.WithValue(context.Background(), requestIDKey{}, "req-1"))
+	req := httptest.NewRequest("GET", "/", nil).WithContext(reqCtx)
+	// 模拟客户端断开连接
+	cancel()
+	r.ServeHTTP(httptest.NewRecorder(), req)
+	if values[0] != "tom" || values[1] != "req-1" || values[2] != nil {
+		t.Fatalf("unexpected values %v", values)
+	}
+	if err != context.Canceled {
+		t.Fatalf("cancellation should propagate, got %v", err)
+	}
+}
+
+func TestKeys(t *testing.T) {
+	r := NewEngine()
+	r.Use(func(c *Context) {
+		c.Set("user", "tom")
+		c.Next()
+	})
+	r.Use(func(c *Context) {
+		// 之后的中间件不会覆盖之前保存的值
+		c.Set("age", 18)
+		c.Next()
+	})
+	r.GET("/", func(c *Context) {
+		if c.GetString("user") != "tom" || c.GetInt("age") != 18 || c.GetString("age") != "" || c.GetBool("admin") {
+			t.Errorf("unexpected keys %v", c.Keys)
+		}
+		if age, ok := GetValue[int](c, "age"); !ok || age != 18 {
+			t.Errorf("unexpected age %v", age)
+		}
+		if _, ok := GetValue[string](c, "age"); ok {
+			t.Error("type mismatch should not be ok")
+		}
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Error("MustGet should panic on missing key")
+				}
+			}()
+			c.MustGet("missing")
+		}()
+
+		// 处理函数启动的goroutine并发读写
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				c.Set(strconv.Itoa(i), i)
+				c.GetString("user")
+			}(i)
+		}
+		wg.Wait()
+		if c.MustGet("9") != 9 {
+			t.Errorf("unexpected keys %v", c.Keys)
+		}
+	})
+	performRequest(r, "GET", "/")
+}
+
+// TestContextPool 并发请求复用Context时不会读到之前请求的状态，需配合 go test -race 运行
+func TestContextPool(t *testing.T) {
+	r := NewEngine()
+	r.Use(func(c *Context) {
+		if c.Keys != nil || c.Writer.Written() || c.Writer.Status() != http.StatusOK {
+			t.Errorf("context is not reset: keys %v, status %d", c.Keys, c.Writer.Status())
+		}
+		c.Next()
+	})
+	var wg sync.WaitGroup
+	copies := make(chan *Context, 100)
+	r.GET("/user/:id", func(c *Context) {
+		c.Set("id", c.Param("id"))
+		cp := c.Copy()
+		wg.Add(1)
+		// 处理函数返回后，在goroutine中继续使用副本
+		go func() {
+			defer wg.Done()
+			time.Sleep(time.Millisecond)
+			if cp.Param("id") != cp.GetString("id") {
+				t.Errorf("copy changed after request: param %s, key %s", cp.Param("id"), cp.GetString("id"))
+			}
+			// 副本写入响应时返回错误而不是panic
+			cp.SetHeader("X-Id", cp.Param("id"))
+			if _, err := cp.Writer.Write([]byte("late")); err != ErrCopiedContext {
+				t.Errorf("unexpected write error %v", err)
+			}
+			copies <- cp
+		}()
+		c.String(http.StatusOK, "%s", c.Param("id"))
+	})
+
+	var clients sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		clients.Add(1)
+		go func(i int) {
+			defer clients.Done()
+			id := strconv.Itoa(i)
+			if w := performRequest(r, "GET", "/user/"+id); w.Body.String() != id {
+				t.Errorf("unexpected body %q for id %s", w.Body.String(), id)
+			}
+		}(i)
+	}
+	clients.Wait()
+	wg.Wait()
+	close(copies)
+
+	seen := make(map[string]bool)
+	for cp := range copies {
+		seen[cp.Param("id")] = true
+		if !cp.IsAborted() {
+			t.Error("copy should not run the handler chain")
+		}
+	}
+	if len(seen) != 100 {
+		t.Fatalf("unexpected copies %d", len(seen))
+	}
+
+	// 放回池中的Context不再引用请求、响应及处理链
+	c := r.pool.Get().(*Context)
+	if c.Req != nil || c.Writer != nil || c.writermem.ResponseWriter != nil || c.handlers != nil || c.Keys != nil {
+		t.Fatalf("pooled context retains request state: %+v", c)
+	}
+}
+
+func BenchmarkServeHTTP(b *testing.B) {
+	r := NewEngine()
+	r.GET("/user/:id", func(c *Context) {
+		c.Status(http.StatusNoContent)
+	})
+	req := httptest.NewRequest("GET", "/user/1", nil)
+	w := httptest.NewRecorder()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		r.ServeHTTP(w, req)
+	}
+}
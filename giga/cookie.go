@@ -0,0 +1,175 @@
+package giga
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+var (
+	// ErrNoSecretKey 未设置Engine.SecretKey时无法使用签名及加密cookie
+	ErrNoSecretKey = errors.New("giga: engine secret key is not set")
+	// ErrInvalidCookie cookie签名校验或解密失败
+	ErrInvalidCookie = errors.New("giga: invalid cookie")
+)
+
+// CookieOptions cookie的属性，Path为空时默认为/
+type CookieOptions struct {
+	Path     string
+	Domain   string
+	MaxAge   int // 单位秒，小于0时删除cookie，为0时为会话cookie
+	Secure   bool
+	HttpOnly bool
+	SameSite http.SameSite
+}
+
+// Cookie 返回请求中的cookie值，值会进行url解码
+func (c *Context) Cookie(name string) (string, error) {
+	cookie, err := c.Req.Cookie(name)
+	if err != nil {
+		return "", err
+	}
+	return url.QueryUnescape(cookie.Value)
+}
+
+// SetCookie 设置cookie，值会进行url编码，例如
+// c.SetCookie("session", id, giga.CookieOptions{MaxAge: 3600, HttpOnly: true, SameSite: http.SameSiteLaxMode})
+func (c *Context) SetCookie(name string, value string, options ...CookieOptions) {
+	opts := CookieOptions{}
+	if len(options) > 0 {
+		opts = options[0]
+	}
+	if opts.Path == "" {
+		opts.Path = "/"
+	}
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     name,
+		Value:    url.QueryEscape(value),
+		Path:     opts.Path,
+		Domain:   opts.Domain,
+		MaxAge:   opts.MaxAge,
+		Secure:   opts.Secure,
+		HttpOnly: opts.HttpOnly,
+		SameSite: opts.SameSite,
+	})
+}
+
+// SetSignedCookie 设置使用Engine.SecretKey签名的cookie，值对客户端可见但无法被篡改
+func (c *Context) SetSignedCookie(name string, value string, options ...CookieOptions) error {
+	key, err := c.secretKey()
+	if err != nil {
+		return err
+	}
+	payload := base64.RawURLEncoding.EncodeToString([]byte(value))
+	c.SetCookie(name, payload+"."+signCookie(key, name, payload), options...)
+	return nil
+}
+
+// SignedCookie 返回签名cookie的值，签名不正确时返回ErrInvalidCookie
+func (c *Context) SignedCookie(name string) (string, error) {
+	key, err := c.secretKey()
+	if err != nil {
+		return "", err
+	}
+	raw, err := c.Cookie(name)
+	if err != nil {
+		return "", err
+	}
+	payload, signature, ok := strings.Cut(raw, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(signCookie(key, name, payload))) {
+		return "", ErrInvalidCookie
+	}
+	value, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return "", ErrInvalidCookie
+	}
+	return string(value), nil
+}
+
+// SetEncryptedCookie 设置使用Engine.SecretKey加密(AES-GCM)的cookie，值对客户端不可见且无法被篡改
+func (c *Context) SetEncryptedCookie(name string, value string, options ...CookieOptions) error {
+	key, err := c.secretKey()
+	if err != nil {
+		return err
+	}
+	aead, err := newCookieCipher(key)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err = rand.Read(nonce); err != nil {
+		return err
+	}
+	// cookie名作为附加数据，防止将一个cookie的值替换为另一个cookie的值
+	sealed := aead.Seal(nonce, nonce, []byte(value), []byte(name))
+	c.SetCookie(name, base64.RawURLEncoding.EncodeToString(sealed), options...)
+	return nil
+}
+
+// EncryptedCookie 返回加密cookie解密后的值，解密失败时返回ErrInvalidCookie
+func (c *Context) EncryptedCookie(name string) (string, error) {
+	key, err := c.secretKey()
+	if err != nil {
+		return "", err
+	}
+	raw, err := c.Cookie(name)
+	if err != nil {
+		return "", err
+	}
+	aead, err := newCookieCipher(key)
+	if err != nil {
+		return "", err
+	}
+	sealed, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return "", ErrInvalidCookie
+	}
+	value, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], []byte(name))
+	if err != nil {
+		return "", ErrInvalidCookie
+	}
+	return string(value), nil
+}
+
+func (c *Context) secretKey() ([]byte, error) {
+	if c.engine == nil || len(c.engine.SecretKey) == 0 {
+		return nil, ErrNoSecretKey
+	}
+	return c.engine.SecretKey, nil
+}
+
+// signCookie 使用HMAC-SHA256对cookie名及值签名
+func signCookie(key []byte, name string, payload string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(name + "=" + payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// newCookieCipher 由SecretKey派生出AES-256的密钥，SecretKey可以是任意长度
+func newCookieCipher(key []byte) (cipher.AEAD, error) {
+	derived := sha256.Sum256(append([]byte("giga-cookie-encryption:"), key...))
+	block, err := aes.NewCipher(derived[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// Redirect 重定向到location，code需为3xx或201，否则panic，调用后状态码已写入，之后不能再修改
+func (c *Context) Redirect(code int, location string) {
+	if (code < http.StatusMultipleChoices || code > http.StatusPermanentRedirect) && code != http.StatusCreated {
+		panic(fmt.Sprintf("giga: cannot redirect with status code %d", code))
+	}
+	c.StatusCode = code
+	http.Redirect(c.Writer, c.Req, location, code)
+	// 非GET、HEAD请求时http.Redirect只调用WriteHeader，不写入响应体，需立即写入状态码
+	c.Writer.WriteHeaderNow()
+}
@@ -2,84 +2,262 @@ package giga
 
 import (
 	"context"
+	"fmt"
+	"html/template"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
-	"strings"
+	"sync"
 	"syscall"
 	"time"
 )
 
 type HandlerFunc func(*Context)
 
+// defaultShutdownTimeout 关闭服务时默认等待请求处理完毕的时间
+const defaultShutdownTimeout = 5 * time.Second
+
 // Engine
 type (
 	RouterGroup struct {
 		prefix      string
 		middlewares []HandlerFunc // support middleware
+		parent      *RouterGroup  // 父分组，注册路由时依次继承各级父分组的中间件
 		engine      *Engine
+		// 路由的匹配条件，子分组继承父分组的条件
+		host    string
+		headers []headerMatcher
 	}
 
 	Engine struct {
 		*RouterGroup
 		router *router
-		groups []*RouterGroup
+		// 按注册顺序保存的全部路由
+		routes []*Route
+		// 命名路由，用于反向生成url
+		namedRoutes map[string]*Route
+		// 复用Context，请求结束后Context会被下一个请求使用，处理函数返回后仍需使用时调用Context.Copy
+		pool sync.Pool
+
+		// Debug 调试模式，启动时打印路由表
+		Debug bool
+
+		// SecretKey 签名及加密cookie使用的密钥
+		SecretKey []byte
+
+		// MaxMultipartMemory 解析multipart表单时内存中最多保存的字节数，超出部分写入临时文件
+		MaxMultipartMemory int64
+		// MaxMultipartSize multipart请求体的最大字节数，超出时返回413，为0时不限制
+		MaxMultipartSize int64
+
+		// RedirectTrailingSlash 请求路径仅末尾多了/时，重定向到已注册的路由，例如 /user/login/ => /user/login
+		RedirectTrailingSlash bool
+		// RedirectFixedPath 请求路径不规范时，清理 ..、. 及重复的/后重定向到已注册的路由，例如 /user//login => /user/login
+		RedirectFixedPath bool
+		// RedirectIgnoreCase 配合RedirectFixedPath使用，忽略大小写查找已注册的路由，例如 /USER/Login => /user/login
+		RedirectIgnoreCase bool
+		// 以上选项均关闭时为严格模式，不规范的请求路径直接返回404
+
+		// ShutdownTimeout 关闭服务时等待请求处理完毕的时间，超时后取消仍在执行的请求的context，小于等于0时为5秒
+		ShutdownTimeout time.Duration
+
+		// SecureJSONPrefix SecureJSON输出json数组时添加的前缀，防止json劫持，默认为 while(1);
+		SecureJSONPrefix string
+		// HTMLAutoReload 渲染html前检查模板文件是否有变化并重新解析，用于开发环境，
+		// 每次渲染都会读取模板文件的信息，生产环境不建议开启
+		HTMLAutoReload bool
+		// html模板及模板中可使用的函数
+		htmlRender *htmlRender
+		funcMap    template.FuncMap
+
+		// NegotiateFormat 内容协商时Accept为空或没有可提供的格式时使用的格式，默认为MIMEJSON
+		NegotiateFormat string
 	}
 )
 
 func NewEngine() *Engine {
 	engine := &Engine{
-		router: newRouter(),
+		router:                newRouter(),
+		namedRoutes:           make(map[string]*Route),
+		RedirectTrailingSlash: true,
+		RedirectFixedPath:     true,
+		Debug:                 true,
+		MaxMultipartMemory:    defaultMultipartMemory,
+		SecureJSONPrefix:      "while(1);",
+		ShutdownTimeout:       defaultShutdownTimeout,
+		NegotiateFormat:       MIMEJSON,
 	}
 	engine.RouterGroup = &RouterGroup{engine: engine}
-	engine.groups = []*RouterGroup{engine.RouterGroup}
+	engine.pool.New = func() interface{} {
+		return &Context{engine: engine}
+	}
 	return engine
 }
 
+// Group 创建子分组，子分组继承当前分组的前缀及中间件
 func (group *RouterGroup) Group(prefix string) *RouterGroup {
-	engine := group.engine
-	newGroup := &RouterGroup{
-		prefix: group.prefix + prefix,
-		engine: group.engine,
+	return &RouterGroup{
+		prefix:  group.prefix + prefix,
+		parent:  group,
+		engine:  group.engine,
+		host:    group.host,
+		headers: group.headers[:len(group.headers):len(group.headers)],
 	}
-	engine.groups = append(engine.groups, newGroup)
-
-	return newGroup
 }
 
-// Use 增加中间件
+// Use 增加中间件，只作用于之后注册的路由，执行顺序为 engine -> 父分组 -> 子分组
 func (group *RouterGroup) Use(middlewares ...HandlerFunc) {
 	group.middlewares = append(group.middlewares, middlewares...)
 }
 
-func (group *RouterGroup) addRoute(method string, comp string, handler HandlerFunc) {
+// combineHandlers 按 engine -> 各级父分组 -> 当前分组 的顺序合并中间件，并追加handlers
+func (group *RouterGroup) combineHandlers(handlers ...HandlerFunc) []HandlerFunc {
+	groups := make([]*RouterGroup, 0)
+	size := len(handlers)
+	for g := group; g != nil; g = g.parent {
+		groups = append(groups, g)
+		size += len(g.middlewares)
+	}
+
+	chain := make([]HandlerFunc, 0, size)
+	for i := len(groups) - 1; i >= 0; i-- {
+		chain = append(chain, groups[i].middlewares...)
+	}
+	return append(chain, handlers...)
+}
+
+// addRoute 注册路由，handlers为路由级别的中间件及请求处理函数，依次追加在分组中间件之后
+// 例如 group.POST("/register", auth, rateLimit, h.UserRegister)
+func (group *RouterGroup) addRoute(method string, comp string, handlers ...HandlerFunc) *Route {
 	pattern := group.prefix + comp
-	log.Printf("Route %4s - %s, group.prefix:%s ", method, pattern, group.prefix)
-	group.engine.router.addRoute(method, pattern, handler)
+	if len(handlers) == 0 {
+		panic(fmt.Sprintf("giga: route '%s %s' must have at least one handler", method, pattern))
+	}
+	// 注册时即绑定完整的处理链，请求时无需再按前缀查找中间件
+	chain := group.combineHandlers(handlers...)
+	router := group.engine.router
+	if group.host != "" {
+		router = router.hostRouter(group.host).router
+	}
+	router.addRoute(method, pattern, chain, group.headers...)
+	route := &Route{Method: method, Pattern: pattern, group: group, handlers: chain}
+	group.engine.routes = append(group.engine.routes, route)
+	return route
 }
 
 // GET 新增Get请求
-func (group *RouterGroup) GET(pattern string, handler HandlerFunc) {
-	group.addRoute("GET", pattern, handler)
+func (group *RouterGroup) GET(pattern string, handlers ...HandlerFunc) *Route {
+	return group.addRoute("GET", pattern, handlers...)
 }
 
 // POST 新增Post请求
-func (group *RouterGroup) POST(pattern string, handler HandlerFunc) {
-	group.addRoute("POST", pattern, handler)
+func (group *RouterGroup) POST(pattern string, handlers ...HandlerFunc) *Route {
+	return group.addRoute("POST", pattern, handlers...)
 }
 
-func (engine *Engine) ServeHTTP(w http.ResponseWriter, req *http.Request) {
-	var middlewares []HandlerFunc
-	// 通过路由找到对应的中间件函数，并将其加入到context里
-	for _, group := range engine.groups {
-		if strings.HasPrefix(req.URL.Path, group.prefix) {
-			middlewares = append(middlewares, group.middlewares...)
+// PUT 新增Put请求
+func (group *RouterGroup) PUT(pattern string, handlers ...HandlerFunc) *Route {
+	return group.addRoute("PUT", pattern, handlers...)
+}
+
+// DELETE 新增Delete请求
+func (group *RouterGroup) DELETE(pattern string, handlers ...HandlerFunc) *Route {
+	return group.addRoute("DELETE", pattern, handlers...)
+}
+
+// PATCH 新增Patch请求
+func (group *RouterGroup) PATCH(pattern string, handlers ...HandlerFunc) *Route {
+	return group.addRoute("PATCH", pattern, handlers...)
+}
+
+// HEAD 新增Head请求，未注册时会自动复用同路径的GET路由
+func (group *RouterGroup) HEAD(pattern string, handlers ...HandlerFunc) *Route {
+	return group.addRoute("HEAD", pattern, handlers...)
+}
+
+// OPTIONS 新增Options请求，未注册时会自动返回该路径允许的请求方法
+func (group *RouterGroup) OPTIONS(pattern string, handlers ...HandlerFunc) *Route {
+	return group.addRoute("OPTIONS", pattern, handlers...)
+}
+
+// Handle 使用任意请求方法注册路由，method需为大写字母，例如 PROPFIND
+func (group *RouterGroup) Handle(method string, pattern string, handlers ...HandlerFunc) *Route {
+	if !isValidMethod(method) {
+		panic("giga: invalid http method " + method)
+	}
+	return group.addRoute(method, pattern, handlers...)
+}
+
+// Any 为所有常用请求方法注册同一个处理函数
+func (group *RouterGroup) Any(pattern string, handlers ...HandlerFunc) {
+	for _, method := range anyMethods {
+		group.addRoute(method, pattern, handlers...)
+	}
+}
+
+// anyMethods Any 注册的全部请求方法
+var anyMethods = []string{
+	http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch,
+	http.MethodHead, http.MethodOptions, http.MethodDelete, http.MethodConnect,
+	http.MethodTrace,
+}
+
+func isValidMethod(method string) bool {
+	if method == "" {
+		return false
+	}
+	for i := 0; i < len(method); i++ {
+		if method[i] < 'A' || method[i] > 'Z' {
+			return false
 		}
 	}
-	c := newContext(w, req)
-	c.handlers = middlewares
+	return true
+}
+
+// NoRoute 设置路由不存在时的处理函数，状态码默认为404，处理函数可通过c.Status等修改
+func (engine *Engine) NoRoute(handlers ...HandlerFunc) {
+	engine.router.noRoute = handlers
+}
+
+// NoMethod 设置路径存在但请求方法不匹配时的处理函数，
+// 调用前响应头已设置Allow，状态码默认为405，处理函数可通过c.Status等修改
+func (engine *Engine) NoMethod(handlers ...HandlerFunc) {
+	engine.router.noMethod = handlers
+}
+
+func (engine *Engine) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	c := engine.pool.Get().(*Context)
+	c.reset(w, req)
 	engine.router.handle(c)
+	// 处理函数只设置了状态码而没有写入响应体时，在此写入状态码
+	c.Writer.WriteHeaderNow()
+	c.release()
+	engine.pool.Put(c)
+}
+
+// newServer 创建http服务，请求context的父context在服务开始关闭ShutdownTimeout后取消，
+// 使仍在执行的请求(包括其中的rpc调用)尽快结束
+func (engine *Engine) newServer(addr string) *http.Server {
+	baseCtx, cancelBase := context.WithCancel(context.Background())
+	server := &http.Server{
+		Addr:        addr,
+		Handler:     engine,
+		BaseContext: func(net.Listener) context.Context { return baseCtx },
+	}
+	server.RegisterOnShutdown(func() {
+		time.AfterFunc(engine.shutdownTimeout(), cancelBase)
+	})
+	return server
+}
+
+// shutdownTimeout 返回ShutdownTimeout，小于等于0时使用默认值，否则请求的context会被立即取消
+func (engine *Engine) shutdownTimeout() time.Duration {
+	if engine.ShutdownTimeout <= 0 {
+		return defaultShutdownTimeout
+	}
+	return engine.ShutdownTimeout
 }
 
 // Run defines the method to start a http server
@@ -90,9 +268,9 @@ func (engine *Engine) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 //}
 
 func (engine *Engine) Run(srvName string, addr string) {
-	server := http.Server{
-		Addr:    addr,
-		Handler: engine,
+	server := engine.newServer(addr)
+	if engine.Debug {
+		engine.PrintRoutes(os.Stdout)
 	}
 	// 优雅启停
 	go func() {
@@ -111,16 +289,13 @@ func (engine *Engine) Run(srvName string, addr string) {
 	// 在此阻塞
 	<-quit
 	log.Printf("server %s shutting down...\n", srvName)
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	// 请求context在ShutdownTimeout后取消，多等待一段时间让请求在取消后返回
+	ctx, cancel := context.WithTimeout(context.Background(), engine.shutdownTimeout()+time.Second)
 	defer cancel()
 
 	if err := server.Shutdown(ctx); err != nil {
-		log.Fatalf("server %s shutdown err, cause by: %s\n", srvName, err)
-	}
-
-	select {
-	case <-ctx.Done():
-		log.Printf("server %s wait shutdown timeout \n", srvName)
+		log.Printf("server %s shutdown err, cause by: %s\n", srvName, err)
+		return
 	}
 	log.Printf("server %s exiting...\n\n", srvName)
 }
@@ -0,0 +1,518 @@
+package giga
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func performRequest(engine *Engine, method, path string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	return w
+}
+
+// newTestContext 从engine的池中取出Context并绑定请求，用于直接测试Context的方法
+func newTestContext(engine *Engine, w http.ResponseWriter, req *http.Request) *Context {
+	c := engine.pool.Get().(*Context)
+	c.reset(w, req)
+	return c
+}
+
+func TestRouterGroupMethods(t *testing.T) {
+	r := NewEngine()
+	handler := func(c *Context) {
+		c.String(http.StatusOK, "%s", c.Method)
+	}
+	r.PUT("/user/:id", handler)
+	r.DELETE("/user/:id", handler)
+	r.PATCH("/user/:id", handler)
+	r.Handle("PROPFIND", "/user/:id", handler)
+	r.Any("/any", handler)
+
+	for _, method := range []string{"PUT", "DELETE", "PATCH", "PROPFIND"} {
+		w := performRequest(r, method, "/user/1")
+		if w.Code != http.StatusOK || w.Body.String() != method {
+			t.Fatalf("%s /user/1: got %d %q", method, w.Code, w.Body.String())
+		}
+	}
+	for _, method := range anyMethods {
+		if w := performRequest(r, method, "/any"); w.Code != http.StatusOK {
+			t.Fatalf("Any should handle %s, got %d", method, w.Code)
+		}
+	}
+}
+
+func TestHandleInvalidMethod(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("Handle with lowercase method should panic")
+		}
+	}()
+	NewEngine().Handle("get", "/", func(c *Context) {})
+}
+
+func TestAutoHeadAndOptions(t *testing.T) {
+	r := NewEngine()
+	r.GET("/hello/:name", func(c *Context) {
+		c.String(http.StatusOK, "hello %s", c.Param("name"))
+	})
+	r.POST("/hello/:name", func(c *Context) {})
+
+	w := performRequest(r, "HEAD", "/hello/giga")
+	if w.Code != http.StatusOK || w.Body.Len() != 0 {
+		t.Fatalf("HEAD should reuse GET without body, got %d %q", w.Code, w.Body.String())
+	}
+	if w.Header().Get("Content-Type") != "text/plain" {
+		t.Fatal("HEAD should keep GET headers")
+	}
+
+	w = performRequest(r, "OPTIONS", "/hello/giga")
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("OPTIONS should return 204, got %d", w.Code)
+	}
+	if allow := w.Header().Get("Allow"); allow != "GET, HEAD, OPTIONS, POST" {
+		t.Fatalf("unexpected Allow header %q", allow)
+	}
+
+	if w = performRequest(r, "OPTIONS", "/missing"); w.Code != http.StatusNotFound {
+		t.Fatalf("OPTIONS on unknown path should 404, got %d", w.Code)
+	}
+}
+
+func TestMethodNotAllowed(t *testing.T) {
+	r := NewEngine()
+	r.GET("/user/:id", func(c *Context) {})
+	r.PUT("/user/:id", func(c *Context) {})
+
+	w := performRequest(r, "POST", "/user/1")
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("expect 405, got %d", w.Code)
+	}
+	if allow := w.Header().Get("Allow"); allow != "GET, HEAD, OPTIONS, PUT" {
+		t.Fatalf("unexpected Allow header %q", allow)
+	}
+	if w = performRequest(r, "POST", "/order/1"); w.Code != http.StatusNotFound {
+		t.Fatalf("expect 404, got %d", w.Code)
+	}
+}
+
+func TestNoRouteAndNoMethod(t *testing.T) {
+	r := NewEngine()
+	r.GET("/user/:id", func(c *Context) {})
+	r.NoRoute(func(c *Context) {
+		c.Fail(http.StatusNotFound, "route not found")
+	})
+	r.NoMethod(func(c *Context) {
+		c.Fail(http.StatusMethodNotAllowed, "method not allowed")
+	})
+
+	w := performRequest(r, "GET", "/order/1")
+	if w.Code != http.StatusNotFound || w.Body.String() != "{\"message\":\"route not found\"}\n" {
+		t.Fatalf("NoRoute not used: %d %q", w.Code, w.Body.String())
+	}
+	w = performRequest(r, "DELETE", "/user/1")
+	if w.Code != http.StatusMethodNotAllowed || w.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("NoMethod not used: %d %q", w.Code, w.Body.String())
+	}
+	if w.Header().Get("Allow") == "" {
+		t.Fatal("Allow header should be set before NoMethod handlers")
+	}
+}
+
+func TestParamInt(t *testing.T) {
+	r := NewEngine()
+	r.GET("/user/:id", func(c *Context) {
+		id, err := c.ParamInt("id")
+		if err != nil {
+			c.Fail(http.StatusBadRequest, err.Error())
+			return
+		}
+		if _, err = c.ParamInt("missing"); err == nil {
+			t.Error("ParamInt should fail on missing param")
+		}
+		c.String(http.StatusOK, "%d", id+1)
+	})
+
+	if w := performRequest(r, "GET", "/user/41"); w.Body.String() != "42" {
+		t.Fatalf("expect 42, got %q", w.Body.String())
+	}
+	if w := performRequest(r, "GET", "/user/abc"); w.Code != http.StatusBadRequest {
+		t.Fatalf("expect 400, got %d", w.Code)
+	}
+}
+
+func TestRedirectPath(t *testing.T) {
+	r := NewEngine()
+	r.RedirectIgnoreCase = true
+	r.GET("/user/login", func(c *Context) {})
+	r.POST("/user/login", func(c *Context) {})
+	r.GET("/user/:id/profile", func(c *Context) {})
+	r.Header("X-Foo", "1").GET("/a", func(c *Context) {})
+	r.GET("/files/:name", func(c *Context) {})
+
+	cases := []struct {
+		method   string
+		path     string
+		code     int
+		location string
+	}{
+		{"GET", "/user/login/", http.StatusMovedPermanently, "/user/login"},
+		{"POST", "/user/login/", http.StatusPermanentRedirect, "/user/login"},
+		{"GET", "/user//login", http.StatusMovedPermanently, "/user/login"},
+		{"GET", "/user/../user/./login?a=1", http.StatusMovedPermanently, "/user/login?a=1"},
+		{"GET", "/USER/Login", http.StatusMovedPermanently, "/user/login"},
+		{"GET", "/User/GIGA/Profile", http.StatusMovedPermanently, "/user/GIGA/profile"},
+		{"GET", "/user/login", http.StatusOK, ""},
+		// 请求头不匹配时不能重定向到自身
+		{"GET", "/a", http.StatusNotFound, ""},
+		{"GET", "/A", http.StatusNotFound, ""},
+		// 解码后的路径需重新转义，%3F不能变为查询参数
+		{"GET", "/files/x%3Fy/?a=1", http.StatusMovedPermanently, "/files/x%3Fy?a=1"},
+	}
+	for _, tc := range cases {
+		w := performRequest(r, tc.method, tc.path)
+		if w.Code != tc.code || w.Header().Get("Location") != tc.location {
+			t.Fatalf("%s %s: expect %d %q, got %d %q", tc.method, tc.path,
+				tc.code, tc.location, w.Code, w.Header().Get("Location"))
+		}
+	}
+
+	// 根路径参数路由不能重定向到 /\evil.com 或 //evil.com 等其他域名
+	r = NewEngine()
+	r.GET("/:name", func(c *Context) {})
+	for _, path := range []string{"/%5Cevil.com/", "//evil.com/", "/%2F/evil.com/"} {
+		if location := performRequest(r, "GET", path).Header().Get("Location"); strings.HasPrefix(location, "//") || strings.HasPrefix(location, "/\\") {
+			t.Fatalf("%s: open redirect to %q", path, location)
+		}
+	}
+	if w := performRequest(r, "GET", "/%5Cevil.com/"); w.Header().Get("Location") != "/%5Cevil.com" {
+		t.Fatalf("unexpected location %q", w.Header().Get("Location"))
+	}
+}
+
+func TestStrictPath(t *testing.T) {
+	r := NewEngine()
+	r.RedirectTrailingSlash = false
+	r.RedirectFixedPath = false
+	r.GET("/user/login", func(c *Context) {})
+
+	for _, path := range []string{"/user/login/", "/user//login", "/USER/login"} {
+		if w := performRequest(r, "GET", path); w.Code != http.StatusNotFound {
+			t.Fatalf("%s should 404 in strict mode, got %d", path, w.Code)
+		}
+	}
+}
+
+func TestURL(t *testing.T) {
+	r := NewEngine()
+	user := r.Group("/user")
+	user.GET("/:id<int>", func(c *Context) {}).Name("user.detail")
+	user.GET("/:id<int>/files/*filepath", func(c *Context) {}).Name("user.file")
+	r.GET("/", func(c *Context) {}).Name("index")
+
+	cases := []struct {
+		url    func() (string, error)
+		expect string
+	}{
+		{func() (string, error) { return r.URL("user.detail", "id", "42") }, "/user/42"},
+		{func() (string, error) { return r.URLMap("user.detail", map[string]string{"id": "7"}) }, "/user/7"},
+		{func() (string, error) { return r.URL("user.file", "id", "1", "filepath", "a b/c.txt") }, "/user/1/files/a%20b/c.txt"},
+		{func() (string, error) { return r.URL("index") }, "/"},
+	}
+	for _, tc := range cases {
+		if url, err := tc.url(); err != nil || url != tc.expect {
+			t.Fatalf("expect %s, got %s, err: %v", tc.expect, url, err)
+		}
+	}
+
+	for _, pairs := range [][]string{{}, {"id"}, {"id", "abc"}, {"name", "42"}} {
+		if _, err := r.URL("user.detail", pairs...); err == nil {
+			t.Fatalf("URL with %v should fail", pairs)
+		}
+	}
+	if _, err := r.URL("user.file", "id", "1"); err == nil {
+		t.Fatal("URL without catch-all param should fail")
+	}
+	if _, err := r.URL("missing"); err == nil {
+		t.Fatal("URL of unknown route should fail")
+	}
+}
+
+func TestRoutes(t *testing.T) {
+	r := NewEngine()
+	r.GET("/", indexHandler)
+	user := r.Group("/user")
+	user.Use(func(c *Context) { c.Next() })
+	user.POST("/login", func(c *Context) {}).Name("user.login")
+
+	routes := r.Routes()
+	if len(routes) != 2 {
+		t.Fatalf("expect 2 routes, got %d", len(routes))
+	}
+	if routes[0].Method != "GET" || routes[0].Pattern != "/" || routes[0].Handler != "giga.indexHandler" {
+		t.Fatalf("unexpected route %+v", routes[0])
+	}
+	login := routes[1]
+	if login.Pattern != "/user/login" || login.Name != "user.login" || login.Prefix != "/user" || login.Middlewares != 1 {
+		t.Fatalf("unexpected route %+v", login)
+	}
+
+	var sb strings.Builder
+	r.PrintRoutes(&sb)
+	if !strings.Contains(sb.String(), "/user/login") {
+		t.Fatalf("route table should contain /user/login:\n%s", sb.String())
+	}
+}
+
+func indexHandler(c *Context) {}
+
+func TestGroupMiddlewares(t *testing.T) {
+	r := NewEngine()
+	trace := func(name string) HandlerFunc {
+		return func(c *Context) {
+			c.Writer.Header().Add("X-Trace", name)
+			c.Next()
+		}
+	}
+	r.Use(trace("engine"))
+	user := r.Group("/user")
+	user.Use(trace("user"))
+	admin := user.Group("/admin")
+	admin.Use(trace("admin"))
+
+	admin.GET("/list", func(c *Context) {})
+	r.GET("/username/:name", func(c *Context) {})
+
+	cases := map[string][]string{
+		"/user/admin/list": {"engine", "user", "admin"},
+		"/username/giga":   {"engine"},
+		"/user/missing":    {"engine"},
+	}
+	for path, expect := range cases {
+		w := performRequest(r, "GET", path)
+		if got := w.Header().Values("X-Trace"); strings.Join(got, ",") != strings.Join(expect, ",") {
+			t.Fatalf("%s: expect middlewares %v, got %v", path, expect, got)
+		}
+	}
+}
+
+func TestRouteHandlersChain(t *testing.T) {
+	r := NewEngine()
+	user := r.Group("/user")
+	user.Use(func(c *Context) {
+		c.Writer.Header().Add("X-Trace", "group")
+		c.Next()
+	})
+	auth := func(c *Context) {
+		if c.Query("token") == "" {
+			c.Fail(http.StatusUnauthorized, "unauthorized")
+			return
+		}
+		c.Writer.Header().Add("X-Trace", "auth")
+		c.Next()
+	}
+	user.POST("/register", auth, func(c *Context) {
+		c.String(http.StatusOK, "registered")
+	})
+	user.POST("/login", func(c *Context) {})
+
+	w := performRequest(r, "POST", "/user/register?token=1")
+	if w.Body.String() != "registered" || strings.Join(w.Header().Values("X-Trace"), ",") != "group,auth" {
+		t.Fatalf("unexpected response %q, trace %v", w.Body.String(), w.Header().Values("X-Trace"))
+	}
+	if w = performRequest(r, "POST", "/user/register"); w.Code != http.StatusUnauthorized {
+		t.Fatalf("route middleware should abort, got %d", w.Code)
+	}
+	if w = performRequest(r, "POST", "/user/login"); w.Code != http.StatusOK {
+		t.Fatalf("route middleware shouldn't apply to /user/login, got %d", w.Code)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("registering without handlers should panic")
+		}
+	}()
+	user.GET("/empty")
+}
+
+func TestHostRouting(t *testing.T) {
+	r := NewEngine()
+	r.Host("admin.example.com").GET("/", func(c *Context) {
+		c.String(http.StatusOK, "admin")
+	})
+	r.Host(":tenant.example.com").GET("/", func(c *Context) {
+		c.String(http.StatusOK, "tenant %s", c.HostParam("tenant"))
+	})
+	r.Host("*.example.org").GET("/", func(c *Context) {
+		c.String(http.StatusOK, "org")
+	})
+	r.GET("/", func(c *Context) {
+		c.String(http.StatusOK, "default")
+	})
+
+	cases := map[string]string{
+		"admin.example.com":      "admin",
+		"Admin.Example.com:8080": "admin",
+		"acme.example.com":       "tenant acme",
+		"a.b.example.org":        "org",
+		"example.org":            "default",
+		"localhost:8080":         "default",
+	}
+	for host, expect := range cases {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Host = host
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Body.String() != expect {
+			t.Fatalf("host %s: expect %q, got %q", host, expect, w.Body.String())
+		}
+	}
+}
+
+func TestHeaderRouting(t *testing.T) {
+	r := NewEngine()
+	api := r.Group("/api")
+	api.Version("2").GET("/user", func(c *Context) {
+		c.String(http.StatusOK, "v2")
+	})
+	api.Header("X-Beta", "").GET("/user", func(c *Context) {
+		c.String(http.StatusOK, "beta")
+	})
+	api.GET("/user", func(c *Context) {
+		c.String(http.StatusOK, "v1")
+	})
+	api.Version("2").GET("/only-v2", func(c *Context) {})
+
+	cases := []struct {
+		header http.Header
+		expect string
+	}{
+		{http.Header{"Accept-Version": {"2"}}, "v2"},
+		{http.Header{"X-Beta": {"1"}}, "beta"},
+		{http.Header{"Accept-Version": {"3"}}, "v1"},
+		{http.Header{}, "v1"},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest("GET", "/api/user", nil)
+		req.Header = tc.header
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Body.String() != tc.expect {
+			t.Fatalf("headers %v: expect %q, got %q", tc.header, tc.expect, w.Body.String())
+		}
+	}
+	if w := performRequest(r, "GET", "/api/only-v2"); w.Code != http.StatusNotFound {
+		t.Fatalf("route without matching headers should 404, got %d", w.Code)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("registering the same headers twice should panic")
+		}
+	}()
+	api.Version("2").GET("/user", func(c *Context) {})
+}
+
+func TestWrapAndMount(t *testing.T) {
+	sub := NewEngine()
+	sub.Use(func(c *Context) {
+		c.Writer.Header().Add("X-Trace", "sub")
+		c.Next()
+	})
+	sub.GET("/users/:id", func(c *Context) {
+		c.String(http.StatusOK, "user %s", c.Param("id"))
+	})
+	sub.GET("/", func(c *Context) {
+		c.String(http.StatusOK, "admin index")
+	})
+
+	r := NewEngine()
+	admin := r.Group("/admin")
+	admin.Use(func(c *Context) {
+		c.Writer.Header().Add("X-Trace", "admin")
+		c.Next()
+	})
+	admin.Mount("/", sub)
+	r.Mount("/std", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte("std " + req.URL.Path))
+	}))
+	r.GET("/wrap", WrapF(func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte("wrapped"))
+	}))
+
+	cases := map[string]string{
+		"/admin/users/7": "user 7",
+		"/admin":         "admin index",
+		"/std/a/b":       "std /a/b",
+		"/std":           "std /",
+		"/wrap":          "wrapped",
+	}
+	for path, expect := range cases {
+		if w := performRequest(r, "GET", path); w.Body.String() != expect {
+			t.Fatalf("%s: expect %q, got %d %q", path, expect, w.Code, w.Body.String())
+		}
+	}
+
+	w := performRequest(r, "GET", "/admin/users/7")
+	if trace := strings.Join(w.Header().Values("X-Trace"), ","); trace != "admin,sub" {
+		t.Fatalf("expect middlewares admin,sub, got %s", trace)
+	}
+	if w = performRequest(r, "POST", "/admin/users/7"); w.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("sub engine should answer 405 itself, got %d", w.Code)
+	}
+}
+
+func TestShutdownCancelsRequests(t *testing.T) {
+	r := NewEngine()
+	r.ShutdownTimeout = 50 * time.Millisecond
+	started := make(chan struct{})
+	result := make(chan error, 1)
+	r.GET("/slow", func(c *Context) {
+		close(started)
+		select {
+		case <-c.Done():
+			result <- c.Err()
+		case <-time.After(5 * time.Second):
+			result <- nil
+		}
+	})
+
+	server := r.newServer("")
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go server.Serve(ln)
+	go http.Get("http://" + ln.Addr().String() + "/slow")
+	<-started
+
+	// 关闭服务超过ShutdownTimeout后，仍在执行的请求的context被取消
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	begin := time.Now()
+	if err = server.Shutdown(ctx); err != nil {
+		t.Fatalf("shutdown failed: %v", err)
+	}
+	if err = <-result; err != context.Canceled {
+		t.Fatalf("in-flight request should be canceled, got %v", err)
+	}
+	if elapsed := time.Since(begin); elapsed < r.ShutdownTimeout {
+		t.Fatalf("request canceled before the grace period, elapsed %v", elapsed)
+	}
+}
+
+func TestShutdownTimeoutDefault(t *testing.T) {
+	r := NewEngine()
+	for _, timeout := range []time.Duration{0, -time.Second} {
+		r.ShutdownTimeout = timeout
+		if r.shutdownTimeout() != defaultShutdownTimeout {
+			t.Fatalf("ShutdownTimeout %v should use default, got %v", timeout, r.shutdownTimeout())
+		}
+	}
+}
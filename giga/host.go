@@ -0,0 +1,160 @@
+package giga
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// hostRouter 绑定了host的路由树
+// host模式按.拆分，支持精确匹配、:参数匹配一级子域名以及*匹配任意多级子域名(只能位于开头)，
+// 例如 admin.example.com、:tenant.example.com、*.example.com
+type hostRouter struct {
+	pattern string
+	labels  []string
+	*router
+}
+
+// hostRouter 返回host模式对应的路由树，不存在时创建
+func (r *router) hostRouter(pattern string) *hostRouter {
+	pattern = strings.ToLower(pattern)
+	for _, h := range r.hosts {
+		if h.pattern == pattern {
+			return h
+		}
+	}
+
+	labels := strings.Split(pattern, ".")
+	for i, label := range labels {
+		if label == "" || label == ":" || (label[0] == '*' && (i > 0 || label != "*")) {
+			panic(fmt.Sprintf("giga: invalid host pattern '%s'", pattern))
+		}
+	}
+	h := &hostRouter{pattern: pattern, labels: labels, router: newRouter()}
+	r.hosts = append(r.hosts, h)
+	// 精确匹配的host优先，其次是带:参数的host，最后是带*的host
+	sort.SliceStable(r.hosts, func(i, j int) bool {
+		return r.hosts[i].priority() < r.hosts[j].priority()
+	})
+	return h
+}
+
+func (h *hostRouter) priority() int {
+	if strings.HasPrefix(h.pattern, "*") {
+		return 2
+	}
+	if strings.Contains(h.pattern, ":") {
+		return 1
+	}
+	return 0
+}
+
+// matchHost 返回与请求host匹配的路由树以及host中的参数，host会去除端口并忽略大小写
+func (r *router) matchHost(host string) (*hostRouter, Params) {
+	if len(r.hosts) == 0 {
+		return nil, nil
+	}
+	host = strings.ToLower(stripPort(host))
+	labels := strings.Split(host, ".")
+	for _, h := range r.hosts {
+		if params, ok := h.match(labels); ok {
+			return h, params
+		}
+	}
+	return nil, nil
+}
+
+func (h *hostRouter) match(labels []string) (Params, bool) {
+	patterns := h.labels
+	if patterns[0] == "*" {
+		// *至少匹配一级子域名
+		if len(labels) <= len(patterns)-1 {
+			return nil, false
+		}
+		labels = labels[len(labels)-len(patterns)+1:]
+		patterns = patterns[1:]
+	}
+	if len(labels) != len(patterns) {
+		return nil, false
+	}
+
+	var params Params
+	for i, pattern := range patterns {
+		if pattern[0] == ':' {
+			params = append(params, Param{Key: pattern[1:], Value: labels[i]})
+		} else if pattern != labels[i] {
+			return nil, false
+		}
+	}
+	return params, true
+}
+
+// stripPort 去除host中的端口，例如 api.example.com:8080 => api.example.com，[::1]:8080 => ::1
+func stripPort(host string) string {
+	if strings.HasPrefix(host, "[") {
+		if i := strings.IndexByte(host, ']'); i > 0 {
+			return host[1:i]
+		}
+		return host
+	}
+	if i := strings.LastIndexByte(host, ':'); i >= 0 {
+		return host[:i]
+	}
+	return host
+}
+
+// headerMatcher 请求头匹配条件，value为空时只要求请求头存在
+type headerMatcher struct {
+	key   string
+	value string
+}
+
+func (m headerMatcher) match(req *http.Request) bool {
+	value := req.Header.Get(m.key)
+	if m.value == "" {
+		return value != ""
+	}
+	return value == m.value
+}
+
+func matchHeaders(headers []headerMatcher, req *http.Request) bool {
+	for _, m := range headers {
+		if !m.match(req) {
+			return false
+		}
+	}
+	return true
+}
+
+// headersKey 将请求头条件转换为与顺序无关的字符串，用于检测重复注册
+func headersKey(headers []headerMatcher) string {
+	keys := make([]string, 0, len(headers))
+	for _, m := range headers {
+		keys = append(keys, http.CanonicalHeaderKey(m.key)+"="+m.value)
+	}
+	sort.Strings(keys)
+	return "[" + strings.Join(keys, ", ") + "]"
+}
+
+// Host 创建绑定host的分组，分组下的路由只处理host匹配的请求，
+// 例如 r.Host("admin.example.com")、r.Host(":tenant.example.com")，参数可通过Context.HostParam获取
+// 未绑定host的路由只处理与所有host分组都不匹配的请求
+func (group *RouterGroup) Host(pattern string) *RouterGroup {
+	newGroup := group.Group("")
+	newGroup.host = pattern
+	return newGroup
+}
+
+// Header 创建要求请求头匹配的分组，value为空时只要求请求头存在。
+// 同一路由可以按不同的请求头条件注册多次，均不匹配时使用不带条件的路由
+func (group *RouterGroup) Header(key string, value string) *RouterGroup {
+	newGroup := group.Group("")
+	newGroup.headers = append(newGroup.headers, headerMatcher{key: key, value: value})
+	return newGroup
+}
+
+// Version 创建按api版本匹配的分组，等同于 Header("Accept-Version", version)
+func (group *RouterGroup) Version(version string) *RouterGroup {
+	return group.Header("Accept-Version", version)
+}
@@ -0,0 +1,216 @@
+package giga
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// html模板，模板名为相对于模板目录的路径，例如 user/list.html，
+// layouts、partials 目录下的文件及以_开头的文件为公共模板，可被所有页面引用，
+// 其余文件为页面，每个页面在公共模板的副本上解析，因此不同页面可以定义同名的块，例如
+//
+//	layouts/base.html: {{define "base"}}<html><body>{{block "content" .}}{{end}}</body></html>{{end}}
+//	user/list.html:    {{template "base" .}}{{define "content"}}...{{end}}
+//
+// 开启Engine.HTMLAutoReload时，渲染前检查模板文件是否有变化，有变化时重新解析，无需重启服务
+type htmlRender struct {
+	mu       sync.RWMutex
+	fsys     fs.FS
+	patterns []string
+	funcMap  template.FuncMap
+
+	shared    *template.Template
+	pages     map[string]*template.Template
+	signature string
+}
+
+// SetFuncMap 设置模板中可使用的函数，需在LoadHTMLGlob、LoadHTMLFS之前调用
+func (engine *Engine) SetFuncMap(funcMap template.FuncMap) {
+	engine.funcMap = funcMap
+}
+
+// LoadHTMLGlob 加载匹配pattern的模板文件，例如 r.LoadHTMLGlob("templates/*/*.html")，
+// 模板名为相对于pattern中不含通配符的目录的路径，解析失败时panic
+func (engine *Engine) LoadHTMLGlob(pattern string) {
+	root := globRoot(pattern)
+	rel, err := filepath.Rel(root, pattern)
+	if err != nil {
+		panic(fmt.Sprintf("giga: invalid html glob %s: %v", pattern, err))
+	}
+	engine.loadHTML(os.DirFS(root), filepath.ToSlash(rel))
+}
+
+// LoadHTMLFS 从文件系统加载匹配patterns的模板文件，例如 r.LoadHTMLFS(templates, "templates/*.html")，
+// 模板名为文件在fsys中的路径，解析失败时panic
+func (engine *Engine) LoadHTMLFS(fsys fs.FS, patterns ...string) {
+	engine.loadHTML(fsys, patterns...)
+}
+
+func (engine *Engine) loadHTML(fsys fs.FS, patterns ...string) {
+	render := &htmlRender{fsys: fsys, patterns: patterns, funcMap: engine.funcMap}
+	if err := render.load(); err != nil {
+		panic(err)
+	}
+	engine.htmlRender = render
+}
+
+// HTML 使用name对应的模板渲染data
+func (c *Context) HTML(code int, name string, data interface{}) {
+	if c.engine == nil || c.engine.htmlRender == nil {
+		c.render(code, "", nil, errors.New("giga: html templates are not loaded"))
+		return
+	}
+	render := c.engine.htmlRender
+	if c.engine.HTMLAutoReload {
+		// 重新解析失败时继续使用之前解析的模板
+		if err := render.reload(); err != nil {
+			log.Printf("reload html templates: %v", err)
+		}
+	}
+
+	render.mu.RLock()
+	t, ok := render.pages[name]
+	if !ok {
+		t = render.shared
+	}
+	render.mu.RUnlock()
+
+	// 先渲染到缓冲区，模板执行出错时可以返回500
+	var buf strings.Builder
+	err := t.ExecuteTemplate(&buf, name, data)
+	c.render(code, "text/html; charset=utf-8", []byte(buf.String()), err)
+}
+
+// files 返回全部模板文件，按路径排序
+func (r *htmlRender) files() ([]string, error) {
+	var files []string
+	seen := make(map[string]bool)
+	for _, pattern := range r.patterns {
+		matches, err := fs.Glob(r.fsys, pattern)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range matches {
+			if info, err := fs.Stat(r.fsys, name); err != nil || info.IsDir() || seen[name] {
+				continue
+			}
+			seen[name] = true
+			files = append(files, name)
+		}
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("giga: no html templates match %v", r.patterns)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// fingerprint 由文件名、大小及修改时间组成，用于判断模板文件是否有变化
+func (r *htmlRender) fingerprint(files []string) string {
+	var sb strings.Builder
+	for _, name := range files {
+		if info, err := fs.Stat(r.fsys, name); err == nil {
+			fmt.Fprintf(&sb, "%s:%d:%d;", name, info.Size(), info.ModTime().UnixNano())
+		}
+	}
+	return sb.String()
+}
+
+func (r *htmlRender) load() error {
+	files, err := r.files()
+	if err != nil {
+		return err
+	}
+
+	shared := template.New("").Funcs(r.funcMap)
+	var pages []string
+	for _, name := range files {
+		if !isSharedTemplate(name) {
+			pages = append(pages, name)
+			continue
+		}
+		if err = parseTemplateFile(shared, r.fsys, name); err != nil {
+			return err
+		}
+	}
+	parsed := make(map[string]*template.Template, len(pages))
+	for _, name := range pages {
+		t, err := shared.Clone()
+		if err != nil {
+			return err
+		}
+		if err = parseTemplateFile(t, r.fsys, name); err != nil {
+			return err
+		}
+		parsed[name] = t
+	}
+
+	r.mu.Lock()
+	r.shared, r.pages, r.signature = shared, parsed, r.fingerprint(files)
+	r.mu.Unlock()
+	return nil
+}
+
+// reload 模板文件有增删或修改时重新解析
+func (r *htmlRender) reload() error {
+	files, err := r.files()
+	if err != nil {
+		return err
+	}
+	signature := r.fingerprint(files)
+	r.mu.RLock()
+	changed := r.signature != signature
+	r.mu.RUnlock()
+	if !changed {
+		return nil
+	}
+	if err = r.load(); err != nil {
+		// 记录失败时的文件信息，文件再次变化前不再重复解析
+		r.mu.Lock()
+		r.signature = signature
+		r.mu.Unlock()
+	}
+	return err
+}
+
+func parseTemplateFile(t *template.Template, fsys fs.FS, name string) error {
+	content, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return err
+	}
+	if _, err = t.New(name).Parse(string(content)); err != nil {
+		return fmt.Errorf("giga: parse html template %s: %w", name, err)
+	}
+	return nil
+}
+
+// isSharedTemplate layouts、partials目录下的文件及以_开头的文件为公共模板
+func isSharedTemplate(name string) bool {
+	if strings.HasPrefix(path.Base(name), "_") {
+		return true
+	}
+	for _, dir := range strings.Split(path.Dir(name), "/") {
+		if dir == "layouts" || dir == "partials" {
+			return true
+		}
+	}
+	return false
+}
+
+// globRoot 返回pattern中不含通配符的目录部分，例如 templates/*/*.html 返回 templates
+func globRoot(pattern string) string {
+	root := filepath.Dir(pattern)
+	for strings.ContainsAny(root, "*?[") && root != filepath.Dir(root) {
+		root = filepath.Dir(root)
+	}
+	return root
+}
@@ -0,0 +1,106 @@
+package giga
+
+import (
+	"html/template"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestHTMLTemplates(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"layouts/base.html":  `{{define "base"}}<title>{{block "title" .}}giga{{end}}</title>{{template "partials/_nav.html" .}}{{block "content" .}}{{end}}{{end}}`,
+		"partials/_nav.html": `<nav>{{upper .User}}</nav>`,
+		"user/list.html":     `{{template "base" .}}{{define "title"}}users{{end}}{{define "content"}}<p>{{.Msg}}</p>{{end}}`,
+		"user/show.html":     `{{template "base" .}}{{define "content"}}<b>{{.User}}</b>{{end}}`,
+	}
+	for name, content := range files {
+		writeTestFile(t, filepath.Join(dir, name), content)
+	}
+
+	r := NewEngine()
+	r.SetFuncMap(template.FuncMap{"upper": strings.ToUpper})
+	r.LoadHTMLGlob(filepath.Join(dir, "*", "*.html"))
+	r.GET("/list", func(c *Context) {
+		c.HTML(http.StatusOK, "user/list.html", H{"User": "tom", "Msg": "<script>"})
+	})
+	r.GET("/show", func(c *Context) {
+		c.HTML(http.StatusOK, "user/show.html", H{"User": "tom"})
+	})
+	r.GET("/missing", func(c *Context) {
+		c.HTML(http.StatusOK, "user/missing.html", nil)
+	})
+
+	// 不同页面定义的同名块互不影响，输出经过html转义
+	w := performRequest(r, "GET", "/list")
+	if w.Code != http.StatusOK || w.Body.String() != "<title>users</title><nav>TOM</nav><p>&lt;script&gt;</p>" {
+		t.Fatalf("unexpected code %d, body %q", w.Code, w.Body.String())
+	}
+	if w.Header().Get("Content-Type") != "text/html; charset=utf-8" {
+		t.Fatalf("unexpected content type %q", w.Header().Get("Content-Type"))
+	}
+	w = performRequest(r, "GET", "/show")
+	if w.Body.String() != "<title>giga</title><nav>TOM</nav><b>tom</b>" {
+		t.Fatalf("unexpected body %q", w.Body.String())
+	}
+	if w = performRequest(r, "GET", "/missing"); w.Code != http.StatusInternalServerError {
+		t.Fatalf("unexpected code %d", w.Code)
+	}
+
+	// 未开启HTMLAutoReload时不会重新解析
+	writeTestFile(t, filepath.Join(dir, "user/show.html"), `{{template "base" .}}{{define "content"}}<i>{{.User}}</i>{{end}}!`)
+	w = performRequest(r, "GET", "/show")
+	if w.Body.String() != "<title>giga</title><nav>TOM</nav><b>tom</b>" {
+		t.Fatalf("template should not be reloaded by default, body %q", w.Body.String())
+	}
+
+	// 开启HTMLAutoReload后修改模板无需重新加载
+	r.HTMLAutoReload = true
+	w = performRequest(r, "GET", "/show")
+	if w.Body.String() != "<title>giga</title><nav>TOM</nav><i>tom</i>!" {
+		t.Fatalf("template should be reloaded, body %q", w.Body.String())
+	}
+
+	// 重新解析失败时继续使用之前的模板
+	writeTestFile(t, filepath.Join(dir, "user/show.html"), `{{if}}broken`)
+	w = performRequest(r, "GET", "/show")
+	if w.Code != http.StatusOK || w.Body.String() != "<title>giga</title><nav>TOM</nav><i>tom</i>!" {
+		t.Fatalf("cached templates should be used, got %d %q", w.Code, w.Body.String())
+	}
+}
+
+func TestLoadHTMLFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"templates/index.html": {Data: []byte(`hello {{.}}`)},
+		"templates/README.md":  {Data: []byte(`not a template`)},
+	}
+	r := NewEngine()
+	r.LoadHTMLFS(fsys, "templates/*.html")
+	r.GET("/", func(c *Context) {
+		c.HTML(http.StatusOK, "templates/index.html", "giga")
+	})
+	if w := performRequest(r, "GET", "/"); w.Body.String() != "hello giga" {
+		t.Fatalf("unexpected body %q", w.Body.String())
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("loading invalid templates should panic")
+		}
+	}()
+	r.LoadHTMLFS(fstest.MapFS{"bad.html": {Data: []byte(`{{if}}`)}}, "*.html")
+}
+
+func writeTestFile(t *testing.T, name string, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(name, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,59 @@
+package giga
+
+import "fmt"
+
+// Set 在Keys中保存键值对，Keys为空时自动创建，可在处理函数启动的goroutine中并发调用
+func (c *Context) Set(key string, value interface{}) {
+	c.keysMu.Lock()
+	defer c.keysMu.Unlock()
+	if c.Keys == nil {
+		c.Keys = make(map[string]interface{})
+	}
+	c.Keys[key] = value
+}
+
+// Get 返回Keys中key对应的值，exists表示key是否存在
+func (c *Context) Get(key string) (value interface{}, exists bool) {
+	c.keysMu.RLock()
+	defer c.keysMu.RUnlock()
+	value, exists = c.Keys[key]
+	return
+}
+
+// MustGet 返回Keys中key对应的值，key不存在时panic
+func (c *Context) MustGet(key string) interface{} {
+	if value, exists := c.Get(key); exists {
+		return value
+	}
+	panic(fmt.Sprintf("giga: key %q does not exist", key))
+}
+
+// GetString 返回key对应的字符串，key不存在或类型不是string时返回空字符串
+func (c *Context) GetString(key string) string {
+	s, _ := GetValue[string](c, key)
+	return s
+}
+
+// GetInt 返回key对应的int，key不存在或类型不是int时返回0
+func (c *Context) GetInt(key string) int {
+	n, _ := GetValue[int](c, key)
+	return n
+}
+
+// GetBool 返回key对应的bool，key不存在或类型不是bool时返回false
+func (c *Context) GetBool(key string) bool {
+	b, _ := GetValue[bool](c, key)
+	return b
+}
+
+// GetValue 返回key对应的T类型的值，key不存在或类型不匹配时ok为false，例如
+//
+//	userService, ok := giga.GetValue[pb.UserServiceClient](c, "user")
+func GetValue[T any](c *Context, key string) (value T, ok bool) {
+	raw, exists := c.Get(key)
+	if !exists {
+		return value, false
+	}
+	value, ok = raw.(T)
+	return value, ok
+}
@@ -0,0 +1,131 @@
+package giga
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// defaultMultipartMemory 解析multipart表单时内存中默认最多保存的字节数
+const defaultMultipartMemory = 32 << 20
+
+// FormFile 返回上传的文件，请求体超出MaxMultipartSize时写入413响应并中断处理链
+func (c *Context) FormFile(name string) (*multipart.FileHeader, error) {
+	if err := c.parseMultipartForm(); err != nil {
+		c.abortIfTooLarge(err)
+		return nil, err
+	}
+	if c.Req.MultipartForm == nil || len(c.Req.MultipartForm.File[name]) == 0 {
+		return nil, http.ErrMissingFile
+	}
+	return c.Req.MultipartForm.File[name][0], nil
+}
+
+// MultipartForm 返回解析后的multipart表单，包括普通字段及上传的文件，
+// 请求体超出MaxMultipartSize时写入413响应并中断处理链
+func (c *Context) MultipartForm() (*multipart.Form, error) {
+	if err := c.parseMultipartForm(); err != nil {
+		c.abortIfTooLarge(err)
+		return nil, err
+	}
+	if c.Req.MultipartForm == nil {
+		return nil, http.ErrNotMultipart
+	}
+	return c.Req.MultipartForm, nil
+}
+
+// SaveUploadedFile 将上传的文件保存到dst，dst所在目录不存在时自动创建
+func (c *Context) SaveUploadedFile(file *multipart.FileHeader, dst string) error {
+	src, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	if err = os.MkdirAll(filepath.Dir(dst), 0750); err != nil {
+		return err
+	}
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	_, err = io.Copy(out, src)
+	return err
+}
+
+// StreamMultipart 逐个读取multipart请求的各个part交给fn处理，整个请求体不会缓存到内存或临时文件，
+// 适用于大文件上传。fn返回error时停止读取并返回该error，请求体超出MaxMultipartSize时写入413响应
+func (c *Context) StreamMultipart(fn func(part *multipart.Part) error) error {
+	c.limitMultipartBody()
+	reader, err := c.Req.MultipartReader()
+	if err != nil {
+		return err
+	}
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return nil
+		}
+		if err == nil {
+			err = fn(part)
+			part.Close()
+		}
+		if err != nil {
+			c.abortIfTooLarge(err)
+			return err
+		}
+	}
+}
+
+// parseMultipartForm 按engine的配置解析表单，请求不是multipart时只解析普通表单
+func (c *Context) parseMultipartForm() error {
+	if c.Req.MultipartForm != nil {
+		return nil
+	}
+	maxMemory := int64(defaultMultipartMemory)
+	if c.engine != nil && c.engine.MaxMultipartMemory > 0 {
+		maxMemory = c.engine.MaxMultipartMemory
+	}
+	c.limitMultipartBody()
+	if err := c.Req.ParseMultipartForm(maxMemory); err != nil && err != http.ErrNotMultipart {
+		return err
+	}
+	return nil
+}
+
+// limitMultipartBody 按MaxMultipartSize限制multipart请求体的大小
+func (c *Context) limitMultipartBody() {
+	if c.engine == nil || c.engine.MaxMultipartSize <= 0 || c.Req.Body == nil {
+		return
+	}
+	if _, ok := c.Req.Body.(*maxBytesBody); ok {
+		return
+	}
+	c.Req.Body = &maxBytesBody{http.MaxBytesReader(c.Writer, c.Req.Body, c.engine.MaxMultipartSize)}
+}
+
+// maxBytesBody 标记请求体已被限制大小，避免重复包装
+type maxBytesBody struct {
+	io.ReadCloser
+}
+
+// abortIfTooLarge 请求体超出限制时写入413响应并中断处理链
+func (c *Context) abortIfTooLarge(err error) bool {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge,
+			H{"message": fmt.Sprintf("request body exceeds %d bytes", maxBytesErr.Limit)})
+		return true
+	}
+	if errors.Is(err, multipart.ErrMessageTooLarge) {
+		c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, H{"message": err.Error()})
+		return true
+	}
+	return false
+}
@@ -0,0 +1,117 @@
+package giga
+
+import (
+	"bytes"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newMultipartRequest(t *testing.T, fields map[string]string, file string, content []byte) *http.Request {
+	body := &bytes.Buffer{}
+	mw := multipart.NewWriter(body)
+	for k, v := range fields {
+		mw.WriteField(k, v)
+	}
+	if file != "" {
+		fw, err := mw.CreateFormFile("avatar", file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fw.Write(content)
+	}
+	mw.Close()
+
+	req := httptest.NewRequest("POST", "/upload", body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	return req
+}
+
+func TestFormFile(t *testing.T) {
+	dir := t.TempDir()
+	r := NewEngine()
+	r.POST("/upload", func(c *Context) {
+		file, err := c.FormFile("avatar")
+		if err != nil {
+			c.Fail(http.StatusBadRequest, err.Error())
+			return
+		}
+		if err = c.SaveUploadedFile(file, filepath.Join(dir, c.PostForm("user"), file.Filename)); err != nil {
+			c.Fail(http.StatusInternalServerError, err.Error())
+			return
+		}
+		c.String(http.StatusOK, "%s %d", file.Filename, file.Size)
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, newMultipartRequest(t, map[string]string{"user": "giga"}, "a.png", []byte("png")))
+	if w.Body.String() != "a.png 3" {
+		t.Fatalf("unexpected response %d %q", w.Code, w.Body.String())
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "giga", "a.png")); string(data) != "png" {
+		t.Fatalf("uploaded file not saved, got %q", data)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, newMultipartRequest(t, nil, "", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("missing file should fail, got %d", w.Code)
+	}
+}
+
+func TestMultipartSizeLimit(t *testing.T) {
+	r := NewEngine()
+	r.MaxMultipartSize = 1024
+	r.POST("/upload", func(c *Context) {
+		if _, err := c.FormFile("avatar"); err != nil {
+			return
+		}
+		c.String(http.StatusOK, "ok")
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, newMultipartRequest(t, nil, "big.bin", bytes.Repeat([]byte("x"), 4096)))
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expect 413, got %d %q", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, newMultipartRequest(t, nil, "small.bin", []byte("x")))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expect 200, got %d %q", w.Code, w.Body.String())
+	}
+}
+
+func TestStreamMultipart(t *testing.T) {
+	r := NewEngine()
+	r.MaxMultipartSize = 1024
+	r.POST("/upload", func(c *Context) {
+		var parts []string
+		err := c.StreamMultipart(func(part *multipart.Part) error {
+			data, err := io.ReadAll(part)
+			parts = append(parts, part.FormName()+"="+string(data))
+			return err
+		})
+		if err != nil {
+			return
+		}
+		c.String(http.StatusOK, strings.Join(parts, ","))
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, newMultipartRequest(t, map[string]string{"user": "giga"}, "a.txt", []byte("hello")))
+	if w.Body.String() != "user=giga,avatar=hello" {
+		t.Fatalf("unexpected response %d %q", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, newMultipartRequest(t, nil, "big.bin", bytes.Repeat([]byte("x"), 4096)))
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expect 413, got %d", w.Code)
+	}
+}
@@ -0,0 +1,297 @@
+package giga
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"mime"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/proto"
+	"gopkg.in/yaml.v3"
+)
+
+// 常用的MIME类型，用于内容协商
+const (
+	MIMEJSON     = "application/json"
+	MIMEXML      = "application/xml"
+	MIMEXML2     = "text/xml"
+	MIMEYAML     = "application/x-yaml"
+	MIMEYAML2    = "application/yaml"
+	MIMEProtoBuf = "application/x-protobuf"
+	MIMEMsgPack  = "application/msgpack"
+	MIMEMsgPack2 = "application/x-msgpack"
+	MIMEHTML     = "text/html"
+	MIMEPlain    = "text/plain"
+)
+
+// jsonpCallbackRegexp JSONP回调函数名只允许标识符及属性访问，防止注入脚本
+var jsonpCallbackRegexp = regexp.MustCompile(`^[a-zA-Z_$][a-zA-Z0-9_$]*(\.[a-zA-Z_$][a-zA-Z0-9_$]*)*$`)
+
+// render 编码成功时写入响应，编码失败时返回500，此时状态码尚未写入，不会产生重复的WriteHeader
+func (c *Context) render(code int, contentType string, data []byte, err error) {
+	if err != nil {
+		http.Error(c.Writer, err.Error(), http.StatusInternalServerError)
+		c.StatusCode = http.StatusInternalServerError
+		return
+	}
+	c.SetHeader("Content-Type", contentType)
+	c.Status(code)
+	c.Writer.Write(data)
+}
+
+// PureJSON 输出json，不转义 <、>、& 等html字符
+func (c *Context) PureJSON(code int, obj interface{}) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	err := encoder.Encode(obj)
+	c.render(code, MIMEJSON, buf.Bytes(), err)
+}
+
+// IndentedJSON 输出缩进格式的json，便于阅读，会增加响应体积
+func (c *Context) IndentedJSON(code int, obj interface{}) {
+	data, err := json.MarshalIndent(obj, "", "    ")
+	c.render(code, MIMEJSON, data, err)
+}
+
+// SecureJSON 输出json，obj为数组或切片时添加Engine.SecureJSONPrefix前缀，防止json劫持
+func (c *Context) SecureJSON(code int, obj interface{}) {
+	data, err := json.Marshal(obj)
+	if err == nil && c.engine != nil && c.engine.SecureJSONPrefix != "" {
+		if kind := reflect.Indirect(reflect.ValueOf(obj)).Kind(); kind == reflect.Slice || kind == reflect.Array {
+			data = append([]byte(c.engine.SecureJSONPrefix), data...)
+		}
+	}
+	c.render(code, MIMEJSON, data, err)
+}
+
+// JSONP 查询参数callback不为空时输出 callback(json);，否则与JSON相同，
+// callback只能为js标识符，例如 jQuery123 或 app.handle，否则返回400
+func (c *Context) JSONP(code int, obj interface{}) {
+	callback := c.Query("callback")
+	if callback == "" {
+		c.JSON(code, obj)
+		return
+	}
+	if !jsonpCallbackRegexp.MatchString(callback) {
+		c.String(http.StatusBadRequest, "400 BAD REQUEST: invalid callback %q\n", callback)
+		return
+	}
+	data, err := json.Marshal(obj)
+	if err == nil {
+		data = []byte(callback + "(" + string(data) + ");")
+	}
+	c.render(code, "application/javascript", data, err)
+}
+
+// XML 输出xml，obj为H时按key排序输出为 <map><key>value</key></map>，嵌套的H输出为 <key>...</key>
+func (c *Context) XML(code int, obj interface{}) {
+	data, err := xml.Marshal(obj)
+	c.render(code, MIMEXML, data, err)
+}
+
+// YAML 输出yaml
+func (c *Context) YAML(code int, obj interface{}) {
+	data, err := yaml.Marshal(obj)
+	c.render(code, MIMEYAML, data, err)
+}
+
+// ProtoBuf 输出protobuf，例如直接返回rpc调用得到的pb消息
+func (c *Context) ProtoBuf(code int, msg proto.Message) {
+	data, err := proto.Marshal(msg)
+	c.render(code, MIMEProtoBuf, data, err)
+}
+
+// MsgPack 输出MessagePack，结构体字段名依次使用msgpack、json tag
+func (c *Context) MsgPack(code int, obj interface{}) {
+	var buf bytes.Buffer
+	encoder := msgpack.NewEncoder(&buf)
+	encoder.SetCustomStructTag("json")
+	encoder.SetSortMapKeys(true)
+	encoder.UseCompactInts(true)
+	err := encoder.Encode(obj)
+	c.render(code, MIMEMsgPack, buf.Bytes(), err)
+}
+
+// MarshalXML 使H可以输出为xml，key按字典序排列，作为顶层元素时元素名为map，
+// 作为结构体字段或嵌套在H中时使用字段名或所在的key
+func (h H) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	// 未指定元素名时，encoding/xml使用类型名H
+	if start.Name.Local == "" || start.Name.Local == "H" {
+		start.Name = xml.Name{Local: "map"}
+	}
+	return h.marshalXML(e, start)
+}
+
+func (h H) marshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	keys := make([]string, 0, len(h))
+	for key := range h {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		element := xml.StartElement{Name: xml.Name{Local: key}}
+		var err error
+		if nested, ok := h[key].(H); ok {
+			err = nested.marshalXML(e, element)
+		} else {
+			err = e.EncodeElement(h[key], element)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(start.End())
+}
+
+// Negotiate 内容协商的配置，各格式的数据为空时使用Data
+type Negotiate struct {
+	// Offered 可提供的格式，按优先级排列，为空时为json、xml、yaml、msgpack，Data为proto.Message时包含protobuf
+	Offered []string
+	// Default Accept为空或没有可提供的格式时使用的格式，为空时使用Engine.NegotiateFormat
+	Default string
+
+	Data         interface{}
+	JSONData     interface{}
+	XMLData      interface{}
+	YAMLData     interface{}
+	MsgPackData  interface{}
+	ProtoBufData proto.Message
+}
+
+// Negotiate 按请求头Accept从config.Offered中选择格式输出，例如
+//
+//	c.Negotiate(http.StatusOK, giga.Negotiate{
+//		Offered:      []string{giga.MIMEJSON, giga.MIMEProtoBuf},
+//		JSONData:     giga.H{"code": res.Code},
+//		ProtoBufData: res,
+//	})
+func (c *Context) Negotiate(code int, config Negotiate) {
+	offered := config.Offered
+	if len(offered) == 0 {
+		offered = []string{MIMEJSON, MIMEXML, MIMEYAML, MIMEMsgPack}
+		if _, ok := config.Data.(proto.Message); ok || config.ProtoBufData != nil {
+			offered = append(offered, MIMEProtoBuf)
+		}
+	}
+
+	format := c.NegotiateFormat(offered...)
+	if format == "" {
+		format = config.Default
+		if format == "" && c.engine != nil {
+			format = c.engine.NegotiateFormat
+		}
+		if format == "" {
+			format = offered[0]
+		}
+	}
+
+	pick := func(data interface{}) interface{} {
+		if data != nil {
+			return data
+		}
+		return config.Data
+	}
+	switch format {
+	case MIMEJSON:
+		c.JSON(code, pick(config.JSONData))
+	case MIMEXML, MIMEXML2:
+		c.XML(code, pick(config.XMLData))
+	case MIMEYAML, MIMEYAML2:
+		c.YAML(code, pick(config.YAMLData))
+	case MIMEMsgPack, MIMEMsgPack2:
+		c.MsgPack(code, pick(config.MsgPackData))
+	case MIMEProtoBuf:
+		msg := config.ProtoBufData
+		if msg == nil {
+			msg, _ = config.Data.(proto.Message)
+		}
+		if msg == nil {
+			c.String(http.StatusInternalServerError, "500 INTERNAL SERVER ERROR: negotiate data is not a proto.Message\n")
+			return
+		}
+		c.ProtoBuf(code, msg)
+	case MIMEPlain:
+		c.String(code, "%v", config.Data)
+	default:
+		c.String(http.StatusNotAcceptable, "406 NOT ACCEPTABLE: %s\n", format)
+	}
+}
+
+// NegotiateFormat 按请求头Accept中各类型的q值及顺序，返回offered中最匹配的格式，
+// 支持 */* 及 application/* 形式的通配，Accept为空或没有匹配的格式时返回空字符串
+func (c *Context) NegotiateFormat(offered ...string) string {
+	accept := c.Req.Header.Get("Accept")
+	if accept == "" {
+		return ""
+	}
+
+	type acceptType struct {
+		mediaType string
+		q         float64
+	}
+	accepted := make([]acceptType, 0)
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if value, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(value, 64); err != nil {
+				continue
+			}
+		}
+		if q > 0 {
+			accepted = append(accepted, acceptType{mediaType, q})
+		}
+	}
+	// q值相同时保持Accept中的顺序
+	sort.SliceStable(accepted, func(i, j int) bool { return accepted[i].q > accepted[j].q })
+
+	for _, a := range accepted {
+		for _, format := range offered {
+			if matchMediaType(a.mediaType, format) {
+				return format
+			}
+		}
+	}
+	return ""
+}
+
+// mediaTypeAliases 同一格式的不同写法，内容协商时视为相同
+var mediaTypeAliases = map[string]string{
+	MIMEXML2:     MIMEXML,
+	MIMEYAML2:    MIMEYAML,
+	MIMEMsgPack2: MIMEMsgPack,
+}
+
+// matchMediaType 判断Accept中的类型pattern是否匹配format，例如 application/* 匹配 application/json
+func matchMediaType(pattern string, format string) bool {
+	pattern = strings.ToLower(pattern)
+	if alias, ok := mediaTypeAliases[pattern]; ok {
+		pattern = alias
+	}
+	if alias, ok := mediaTypeAliases[format]; ok {
+		format = alias
+	}
+	if pattern == "*/*" || pattern == format {
+		return true
+	}
+	prefix, ok := strings.CutSuffix(pattern, "/*")
+	if !ok {
+		return false
+	}
+	major, _, _ := strings.Cut(format, "/")
+	return strings.EqualFold(prefix, major)
+}
@@ -0,0 +1,133 @@
+package giga
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func performAccept(engine *Engine, path string, accept string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", path, nil)
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	return w
+}
+
+func TestRenderers(t *testing.T) {
+	r := NewEngine()
+	r.GET("/xml", func(c *Context) { c.XML(http.StatusOK, H{"name": "giga", "age": 3}) })
+	r.GET("/xml/nested", func(c *Context) { c.XML(http.StatusOK, H{"user": H{"id": 1}, "H": H{}}) })
+	r.GET("/yaml", func(c *Context) { c.YAML(http.StatusOK, H{"name": "giga"}) })
+	r.GET("/pure", func(c *Context) { c.PureJSON(http.StatusOK, H{"html": "<b>"}) })
+	r.GET("/indented", func(c *Context) { c.IndentedJSON(http.StatusOK, H{"a": 1}) })
+	r.GET("/secure", func(c *Context) { c.SecureJSON(http.StatusOK, []int{1, 2}) })
+	r.GET("/jsonp", func(c *Context) { c.JSONP(http.StatusOK, H{"a": 1}) })
+	r.GET("/msgpack", func(c *Context) {
+		c.MsgPack(http.StatusOK, struct {
+			Name string `json:"name"`
+			Age  int    `msgpack:"age"`
+			Tags []string
+		}{"giga", 3, nil})
+	})
+	r.GET("/error", func(c *Context) { c.XML(http.StatusOK, make(chan int)) })
+
+	tests := []struct {
+		path        string
+		contentType string
+		body        string
+	}{
+		{"/xml", MIMEXML, "<map><age>3</age><name>giga</name></map>"},
+		{"/xml/nested", MIMEXML, "<map><H></H><user><id>1</id></user></map>"},
+		{"/yaml", MIMEYAML, "name: giga\n"},
+		{"/pure", MIMEJSON, "{\"html\":\"<b>\"}\n"},
+		{"/indented", MIMEJSON, "{\n    \"a\": 1\n}"},
+		{"/secure", MIMEJSON, "while(1);[1,2]"},
+		{"/jsonp?callback=app.cb", "application/javascript", "app.cb({\"a\":1});"},
+		{"/msgpack", MIMEMsgPack, "\x83\xa4name\xa4giga\xa3age\x03\xa4Tags\xc0"},
+	}
+	for _, tt := range tests {
+		w := performRequest(r, "GET", tt.path)
+		if w.Code != http.StatusOK || w.Header().Get("Content-Type") != tt.contentType || w.Body.String() != tt.body {
+			t.Errorf("%s: unexpected code %d, content type %q, body %q", tt.path, w.Code, w.Header().Get("Content-Type"), w.Body.String())
+		}
+	}
+
+	if w := performRequest(r, "GET", "/jsonp?callback=alert(1)"); w.Code != http.StatusBadRequest {
+		t.Errorf("invalid callback: unexpected code %d", w.Code)
+	}
+	// 编码失败时返回500
+	if w := performRequest(r, "GET", "/error"); w.Code != http.StatusInternalServerError {
+		t.Errorf("encode error: unexpected code %d", w.Code)
+	}
+}
+
+func TestMsgPackEmbedded(t *testing.T) {
+	type Base struct {
+		ID int `json:"id"`
+	}
+	r := NewEngine()
+	r.GET("/", func(c *Context) {
+		c.MsgPack(http.StatusOK, struct {
+			Base
+			Name string `msgpack:"name"`
+		}{Base{1}, "giga"})
+	})
+	var decoded map[string]interface{}
+	if err := msgpack.Unmarshal(performRequest(r, "GET", "/").Body.Bytes(), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	// 嵌入的结构体字段展开到外层
+	if decoded["id"] != int8(1) || decoded["name"] != "giga" {
+		t.Fatalf("unexpected msgpack %v", decoded)
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	r := NewEngine()
+	msg := wrapperspb.String("giga")
+	r.GET("/", func(c *Context) {
+		c.Negotiate(http.StatusOK, Negotiate{
+			Offered:      []string{MIMEJSON, MIMEXML, MIMEProtoBuf},
+			Data:         H{"value": "giga"},
+			ProtoBufData: msg,
+		})
+	})
+	r.GET("/yaml", func(c *Context) {
+		c.Negotiate(http.StatusOK, Negotiate{Data: H{"value": "giga"}, Default: MIMEYAML})
+	})
+
+	tests := []struct {
+		path        string
+		accept      string
+		contentType string
+	}{
+		{"/", "", MIMEJSON},
+		{"/", "application/xml", MIMEXML},
+		{"/", "text/html;q=0.9, application/x-protobuf", MIMEProtoBuf},
+		{"/", "application/json;q=0.5, application/xml;q=0.8", MIMEXML},
+		{"/", "application/*", MIMEJSON},
+		{"/", "image/png", MIMEJSON},
+		{"/yaml", "", MIMEYAML},
+		{"/yaml", "application/x-msgpack", MIMEMsgPack},
+	}
+	for _, tt := range tests {
+		w := performAccept(r, tt.path, tt.accept)
+		if w.Code != http.StatusOK || w.Header().Get("Content-Type") != tt.contentType {
+			t.Errorf("%s %q: unexpected code %d, content type %q", tt.path, tt.accept, w.Code, w.Header().Get("Content-Type"))
+		}
+	}
+
+	w := performAccept(r, "/", MIMEProtoBuf)
+	expected, _ := proto.Marshal(msg)
+	if !bytes.Equal(w.Body.Bytes(), expected) {
+		t.Fatalf("unexpected protobuf body %q", w.Body.String())
+	}
+}
@@ -0,0 +1,183 @@
+package giga
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+)
+
+// ResponseWriter 对http.ResponseWriter的封装，记录状态码、写入的字节数以及是否已写入。
+// 状态码在第一次写入响应体或调用WriteHeaderNow时才真正写入，之前可以多次修改。
+// 总是实现http.Flusher、http.Hijacker、http.Pusher及io.ReaderFrom，因此类型断言总会成功，
+// 底层不支持时：Flush不做任何处理，FlushError、Hijack、Push返回http.ErrNotSupported，ReadFrom退化为io.Copy，
+// 调用方需检查返回的错误，或使用 http.NewResponseController(c.Writer) 判断是否支持
+type ResponseWriter interface {
+	http.ResponseWriter
+	http.Flusher
+	http.Hijacker
+	http.Pusher
+	io.ReaderFrom
+	io.StringWriter
+
+	// Status 返回响应的状态码，未设置时为200
+	Status() int
+	// Size 返回已写入的响应体字节数
+	Size() int
+	// Written 状态码是否已写入
+	Written() bool
+	// WriteHeaderNow 立即写入状态码及响应头
+	WriteHeaderNow()
+	// FlushError 与Flush相同，底层不支持时返回http.ErrNotSupported
+	FlushError() error
+	// Unwrap 返回底层的http.ResponseWriter，供http.ResponseController使用
+	Unwrap() http.ResponseWriter
+}
+
+type responseWriter struct {
+	http.ResponseWriter
+	status   int
+	size     int
+	written  bool
+	skipBody bool // HEAD请求复用GET路由时，只保留响应头而丢弃响应体
+}
+
+var _ ResponseWriter = (*responseWriter)(nil)
+
+func (w *responseWriter) reset(writer http.ResponseWriter) {
+	w.ResponseWriter = writer
+	w.status = http.StatusOK
+	w.size = 0
+	w.written = false
+	w.skipBody = false
+}
+
+// WriteHeader 记录状态码，延迟到写入响应体时再写入，已写入后再调用会被忽略。
+// 1xx状态码(例如 103 Early Hints)会直接写入
+func (w *responseWriter) WriteHeader(code int) {
+	if code >= 100 && code < 200 && code != http.StatusSwitchingProtocols {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	if code > 0 && !w.written {
+		w.status = code
+	}
+}
+
+func (w *responseWriter) WriteHeaderNow() {
+	if !w.written {
+		w.written = true
+		w.ResponseWriter.WriteHeader(w.status)
+	}
+}
+
+func (w *responseWriter) Write(data []byte) (int, error) {
+	w.WriteHeaderNow()
+	if w.skipBody {
+		return len(data), nil
+	}
+	n, err := w.ResponseWriter.Write(data)
+	w.size += n
+	return n, err
+}
+
+func (w *responseWriter) WriteString(s string) (int, error) {
+	w.WriteHeaderNow()
+	if w.skipBody {
+		return len(s), nil
+	}
+	n, err := io.WriteString(w.ResponseWriter, s)
+	w.size += n
+	return n, err
+}
+
+// ReadFrom 底层实现了io.ReaderFrom时直接使用，例如*http.response可借此使用sendfile
+func (w *responseWriter) ReadFrom(r io.Reader) (int64, error) {
+	w.WriteHeaderNow()
+	if w.skipBody {
+		return io.Copy(io.Discard, r)
+	}
+	var n int64
+	var err error
+	if rf, ok := w.ResponseWriter.(io.ReaderFrom); ok {
+		n, err = rf.ReadFrom(r)
+	} else {
+		n, err = io.Copy(w.ResponseWriter, r)
+	}
+	w.size += int(n)
+	return n, err
+}
+
+func (w *responseWriter) Status() int {
+	return w.status
+}
+
+func (w *responseWriter) Size() int {
+	return w.size
+}
+
+func (w *responseWriter) Written() bool {
+	return w.written
+}
+
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Flush 写入状态码并将缓冲的数据发送给客户端，底层不支持时不做任何处理，状态码仍可修改
+func (w *responseWriter) Flush() {
+	w.FlushError()
+}
+
+// FlushError 与Flush相同，底层不支持时返回http.ErrNotSupported，供http.ResponseController使用
+func (w *responseWriter) FlushError() error {
+	switch flusher := w.ResponseWriter.(type) {
+	case interface{ FlushError() error }:
+		w.WriteHeaderNow()
+		return flusher.FlushError()
+	case http.Flusher:
+		w.WriteHeaderNow()
+		flusher.Flush()
+		return nil
+	}
+	return http.ErrNotSupported
+}
+
+// Hijack 接管底层连接，例如用于websocket，底层不支持时返回错误
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("giga: response writer does not implement http.Hijacker: %w", http.ErrNotSupported)
+	}
+	// 接管后由调用方负责写入，不再写入状态码
+	w.written = true
+	return hijacker.Hijack()
+}
+
+// Push http/2服务端推送，底层不支持时返回http.ErrNotSupported
+func (w *responseWriter) Push(target string, opts *http.PushOptions) error {
+	if pusher, ok := w.ResponseWriter.(http.Pusher); ok {
+		return pusher.Push(target, opts)
+	}
+	return http.ErrNotSupported
+}
+
+// ErrCopiedContext Context.Copy返回的副本不能写入响应
+var ErrCopiedContext = errors.New("giga: cannot write response from a copied context")
+
+// copiedResponseWriter Context.Copy返回的副本使用的Writer，响应头只保存在副本中，写入响应体时返回ErrCopiedContext
+type copiedResponseWriter struct {
+	header http.Header
+}
+
+func (w *copiedResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *copiedResponseWriter) Write([]byte) (int, error) {
+	return 0, ErrCopiedContext
+}
+
+func (w *copiedResponseWriter) WriteHeader(int) {}
@@ -1,23 +1,36 @@
 package giga
 
 import (
-	"fmt"
 	"net/http"
+	"net/url"
+	"path"
+	"sort"
 	"strings"
+	"sync"
 )
 
 // roots key eg, roots['GET'] roots['POST']
-// handlers key eg, ['GET-/index/:id/detail', 'POST-/user/login']
 type router struct {
-	roots    map[string]*node
-	handlers map[string]HandlerFunc
+	roots map[string]*node
+	// 绑定了host的路由树，例如 admin.example.com、:tenant.example.com，精确匹配的host在前
+	hosts []*hostRouter
+	// 参数切片池，查找路由时复用，避免每次请求分配内存
+	paramsPool sync.Pool
+	maxParams  int
+	// 路由不存在(404)以及请求方法不匹配(405)时的处理函数，为空时使用默认的纯文本响应
+	noRoute  []HandlerFunc
+	noMethod []HandlerFunc
 }
 
 func newRouter() *router {
-	return &router{
-		roots:    make(map[string]*node, 0),
-		handlers: make(map[string]HandlerFunc, 0),
+	r := &router{
+		roots: make(map[string]*node, 0),
 	}
+	r.paramsPool.New = func() interface{} {
+		ps := make(Params, 0, r.maxParams)
+		return &ps
+	}
+	return r
 }
 
 // Only one * is allowed
@@ -37,65 +50,255 @@ func parsePattern(pattern string) []string {
 	return parts
 }
 
-func (r *router) addRoute(method string, pattern string, handler HandlerFunc) {
+// cleanPath 返回规范的路径，去除 ..、. 、重复的/以及末尾的/，例如 /user/../user//login/ => /user/login
+func cleanPath(p string) string {
+	if p == "" || p[0] != '/' {
+		p = "/" + p
+	}
+	return path.Clean(p)
+}
+
+// addRoute 注册路由，headers不为空时，只有请求头满足全部条件才会使用该处理链
+func (r *router) addRoute(method string, pattern string, handlers []HandlerFunc, headers ...headerMatcher) {
 	parts := parsePattern(pattern)
+	pattern = "/" + strings.Join(parts, "/")
 
 	if _, ok := r.roots[method]; !ok {
 		// 如果不存在该方法的根节点
 		r.roots[method] = &node{}
 	}
 
-	// 将路由插入
-	r.roots[method].insert(pattern, parts, 0)
-	key := method + "-" + pattern
-	r.handlers[key] = handler
-}
+	// 将路由插入，静态片段压缩存储，:参数及*通配单独成节点，重复或冲突的路由会在此处panic
+	n := r.roots[method]
+	paramNames := make([]string, 0)
+	static := "/"
+	for i, part := range parts {
+		if i > 0 {
+			static += "/"
+		}
+		if part[0] == ':' || part[0] == '*' {
+			n = n.addStatic(static).addWild(part, pattern)
+			name, _ := splitParam(part)
+			paramNames = append(paramNames, name)
+			static = ""
+			continue
+		}
+		static += part
+	}
+	n = n.addStatic(static)
+	n.pattern = pattern
+	n.paramNames = paramNames
+	n.addHandlers(handlers, headers)
 
-// 解析了:和*两种匹配符的参数，返回一个 map 。
-// 例如/index/1/detail匹配到/index/:id/detail，返回的解析结果为：{id: "1"}
-// 例如path/file/log 匹配到/path/*filepath，解析结果为{filepath: "file/log"}
-func (r *router) getRoute(method string, path string) (*node, map[string]string) {
-	searchParts := parsePattern(path)
-	params := make(map[string]string, 0)
+	if len(paramNames) > r.maxParams {
+		r.maxParams = len(paramNames)
+	}
+}
 
+// getRoute 查找路由，同时解析了:和*两种匹配符的参数。
+// 例如/index/1/detail匹配到/index/:id/detail，返回的解析结果为：[{id 1}]
+// 例如/path/file/log 匹配到/path/*filepath，解析结果为[{filepath file/log}]
+// 返回的参数取自参数池，使用完毕后需调用putParams归还
+func (r *router) getRoute(method string, path string) (*node, *Params) {
 	root, ok := r.roots[method]
 	if !ok {
 		return nil, nil
 	}
 
-	node := root.search(searchParts, 0)
+	ps := r.paramsPool.Get().(*Params)
+	node := root.search(path, ps)
 	if node == nil {
+		r.putParams(ps)
 		return nil, nil
 	}
-	parts := parsePattern(node.pattern)
-	for index, part := range parts {
-		// 例如/index/1/detail匹配到/index/:id/detail，返回的解析结果为：{id: "1"}
-		if part[0] == ':' {
-			params[part[1:]] = searchParts[index]
-		}
-		// 例如path/file/log 匹配到/path/*filepath，解析结果为{filepath: "file/log"}
-		if part[0] == '*' && len(part) > 1 {
-			params[part[1:]] = strings.Join(searchParts[index:], "/")
-			break
-		}
+	// 参数值按出现顺序收集，参数名取自叶子节点上预先解析的结果
+	for i, name := range node.paramNames {
+		(*ps)[i].Key = name
+	}
+	return node, ps
+}
+
+// putParams 将参数切片归还参数池
+func (r *router) putParams(ps *Params) {
+	if ps == nil {
+		return
 	}
-	return node, params
+	*ps = (*ps)[:0]
+	r.paramsPool.Put(ps)
 }
 
 func (r *router) handle(c *Context) {
-	node, params := r.getRoute(c.Method, c.Path)
+	// 按host选择路由树，没有匹配的host时使用默认的路由树
+	rt := r
+	if h, hostParams := r.matchHost(c.Req.Host); h != nil {
+		rt = h.router
+		c.HostParams = hostParams
+	}
+
+	method := c.Method
+	node, params := rt.getRoute(method, c.Path)
+	if node == nil && method == "HEAD" {
+		// 未注册HEAD路由时复用GET路由，并丢弃响应体
+		if node, params = rt.getRoute("GET", c.Path); node != nil {
+			c.writermem.skipBody = true
+		}
+	}
+	var handlers []HandlerFunc
 	if node != nil {
-		c.Params = params
-		// 找到请求处理函数
-		key := c.Method + "-" + node.pattern
-		fmt.Printf("r.handers: %+v \n", r.handlers)
-		// 将请求处理函数也加入到context.handler中
-		c.handlers = append(c.handlers, r.handlers[key])
-	} else {
-		c.handlers = append(c.handlers, func(c *Context) {
-			c.String(http.StatusNotFound, "404 NOT FOUND: %s\n", c.Path)
+		// 请求处理完毕后归还参数切片
+		defer rt.putParams(params)
+		// 注册时已绑定完整的中间件及请求处理函数
+		handlers = node.handlersFor(c.Req)
+	}
+
+	// 未匹配到路由时，只执行engine级别的中间件
+	if handlers != nil {
+		c.Params = *params
+		c.handlers = handlers
+	} else if location, ok := rt.redirectLocation(c); ok {
+		// 不规范的请求路径重定向到已注册的路由，GET请求使用301，其余请求使用308以保留请求方法和请求体
+		code := http.StatusMovedPermanently
+		if method != "GET" && method != "HEAD" {
+			code = http.StatusPermanentRedirect
+		}
+		c.handlers = c.engine.combineHandlers(func(c *Context) {
+			c.SetHeader("Location", location)
+			c.Status(code)
 		})
+	} else if allow := rt.allowed(c); allow != "" {
+		// 路径存在但请求方法不匹配
+		c.SetHeader("Allow", allow)
+		if method == "OPTIONS" {
+			// 未注册OPTIONS路由时，自动返回该路径允许的请求方法
+			c.handlers = c.engine.combineHandlers(func(c *Context) {
+				c.Status(http.StatusNoContent)
+			})
+		} else if len(r.noMethod) > 0 {
+			// 处理函数未写入响应时，默认返回405
+			c.Writer.WriteHeader(http.StatusMethodNotAllowed)
+			c.handlers = c.engine.combineHandlers(r.noMethod...)
+		} else {
+			c.handlers = c.engine.combineHandlers(func(c *Context) {
+				c.String(http.StatusMethodNotAllowed, "405 METHOD NOT ALLOWED: %s %s\n", c.Method, c.Path)
+			})
+		}
+	} else if len(r.noRoute) > 0 {
+		c.Writer.WriteHeader(http.StatusNotFound)
+		c.handlers = c.engine.combineHandlers(r.noRoute...)
+	} else {
+		c.handlers = c.engine.combineHandlers(defaultNotFound)
 	}
 	// 执行中间件链路函数和请求处理函数
 	c.Next()
 }
+
+// defaultNotFound 未设置NoRoute时的404处理函数
+func defaultNotFound(c *Context) {
+	c.String(http.StatusNotFound, "404 NOT FOUND: %s\n", c.Path)
+}
+
+// notFound 在处理函数中返回404，例如静态文件不存在，使用Engine.NoRoute设置的处理函数，
+// engine级别的中间件已经执行过，只执行NoRoute的处理函数，之后继续执行原处理链
+func (c *Context) notFound() {
+	if c.engine == nil || len(c.engine.router.noRoute) == 0 {
+		defaultNotFound(c)
+		return
+	}
+	c.Writer.WriteHeader(http.StatusNotFound)
+	handlers, index := c.handlers, c.index
+	c.handlers, c.index = c.engine.router.noRoute, -1
+	c.Next()
+	aborted := c.IsAborted()
+	c.handlers, c.index = handlers, index
+	if aborted {
+		c.Abort()
+	}
+}
+
+// redirectLocation 返回重定向的Location，路径需重新转义，例如 %3F 解码后的 ? 不能变为查询参数，
+// 以 // 或 /\ 开头的路径会被浏览器视为其他域名，不进行重定向
+func (r *router) redirectLocation(c *Context) (string, bool) {
+	p, ok := r.redirectPath(c)
+	if !ok {
+		return "", false
+	}
+	location := (&url.URL{Path: p}).EscapedPath()
+	if strings.HasPrefix(location, "//") || strings.HasPrefix(location, "/\\") {
+		return "", false
+	}
+	if c.Req.URL.RawQuery != "" {
+		location += "?" + c.Req.URL.RawQuery
+	}
+	return location, true
+}
+
+// redirectPath 按engine的重定向选项，查找请求路径对应的已注册路由的规范路径
+func (r *router) redirectPath(c *Context) (string, bool) {
+	engine := c.engine
+	if engine == nil || c.Method == "CONNECT" || len(c.Path) <= 1 {
+		return "", false
+	}
+	methods := []string{c.Method}
+	if c.Method == "HEAD" {
+		methods = append(methods, "GET")
+	}
+
+	for _, method := range methods {
+		root, ok := r.roots[method]
+		if !ok {
+			continue
+		}
+		if p := c.Path; engine.RedirectTrailingSlash && p[len(p)-1] == '/' {
+			if r.exists(method, p[:len(p)-1], c.Req) {
+				return p[:len(p)-1], true
+			}
+		}
+		if engine.RedirectFixedPath {
+			fixed := cleanPath(c.Path)
+			if fixed != c.Path && r.exists(method, fixed, c.Req) {
+				return fixed, true
+			}
+			if engine.RedirectIgnoreCase {
+				// 修正后的路径需与请求路径不同，且在请求头等匹配条件下存在，否则会重定向到自身
+				if buf := root.searchFold(fixed, make([]byte, 0, len(fixed))); buf != nil {
+					if folded := string(buf); folded != c.Path && r.exists(method, folded, c.Req) {
+						return folded, true
+					}
+				}
+			}
+		}
+	}
+	return "", false
+}
+
+// exists 判断该请求方法下是否存在匹配path及请求头条件的路由
+func (r *router) exists(method string, path string, req *http.Request) bool {
+	node, params := r.getRoute(method, path)
+	r.putParams(params)
+	return node != nil && node.handlersFor(req) != nil
+}
+
+// allowed 返回该路径所有已注册的请求方法，用于填充Allow头，例如 "GET, HEAD, OPTIONS"
+func (r *router) allowed(c *Context) string {
+	methods := make(map[string]bool)
+	for method := range r.roots {
+		if r.exists(method, c.Path, c.Req) {
+			methods[method] = true
+		}
+	}
+	if len(methods) == 0 {
+		return ""
+	}
+	// GET路由隐含HEAD，任意路由隐含OPTIONS
+	if methods["GET"] {
+		methods["HEAD"] = true
+	}
+	methods["OPTIONS"] = true
+
+	allow := make([]string, 0, len(methods))
+	for method := range methods {
+		allow = append(allow, method)
+	}
+	sort.Strings(allow)
+	return strings.Join(allow, ", ")
+}
@@ -37,9 +37,179 @@ func TestGetRoute(t *testing.T) {
 		t.Fatal("should match /hello/:name")
 	}
 
-	if params["name"] != "makabaka" {
+	if params.ByName("name") != "makabaka" {
 		t.Fatal("name should be equal to 'makabaka'")
 	}
 
-	fmt.Printf("matched path: %s, params['name']: %s\n", node.pattern, params["name"])
+	fmt.Printf("matched path: %s, params['name']: %s\n", node.pattern, params.ByName("name"))
+}
+
+func TestRoutePriority(t *testing.T) {
+	r := newRouter()
+	r.addRoute("GET", "/a/*filepath", nil)
+	r.addRoute("GET", "/a/:id/x", nil)
+	r.addRoute("GET", "/a/b/y", nil)
+
+	cases := map[string]string{
+		"/a/b/y":   "/a/b/y",
+		"/a/b/x":   "/a/:id/x",
+		"/a/c/x":   "/a/:id/x",
+		"/a/b/z/w": "/a/*filepath",
+	}
+	for path, pattern := range cases {
+		node, _ := r.getRoute("GET", path)
+		if node == nil || node.pattern != pattern {
+			t.Fatalf("%s should match %s, got %+v", path, pattern, node)
+		}
+	}
+}
+
+func TestRouteConflict(t *testing.T) {
+	conflicts := [][2]string{
+		{"/a/:id", "/a/:name"},
+		{"/a/:id/x", "/a/:name/y"},
+		{"/a/*filepath", "/a/*name"},
+		{"/a/:id", "/a/:id/"},
+		{"/a/b", "/a/b"},
+	}
+	for _, patterns := range conflicts {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("registering %s after %s should panic", patterns[1], patterns[0])
+				}
+			}()
+			r := newRouter()
+			r.addRoute("GET", patterns[0], nil)
+			r.addRoute("GET", patterns[1], nil)
+		}()
+	}
+}
+
+func TestGetRouteParams(t *testing.T) {
+	r := newTestRouter()
+	r.addRoute("GET", "/hello/:name/:action", nil)
+
+	cases := []struct {
+		path    string
+		pattern string
+		params  Params
+	}{
+		{"/", "/", Params{}},
+		{"/hello/b/c", "/hello/b/c", Params{}},
+		{"/hello/b/d", "/hello/:name/:action", Params{{"name", "b"}, {"action", "d"}}},
+		{"/hi/giga", "/hi/:name", Params{{"name", "giga"}}},
+		{"/assets/css/a.css", "/assets/*filepath", Params{{"filepath", "css/a.css"}}},
+	}
+	for _, tc := range cases {
+		node, params := r.getRoute("GET", tc.path)
+		if node == nil || node.pattern != tc.pattern {
+			t.Fatalf("%s should match %s, got %+v", tc.path, tc.pattern, node)
+		}
+		if !reflect.DeepEqual(*params, tc.params) {
+			t.Fatalf("%s: expect params %v, got %v", tc.path, tc.params, *params)
+		}
+		r.putParams(params)
+	}
+
+	for _, path := range []string{"/hello", "/hello/", "/hi", "/assets", "/assets/", "/hey", "/hello//giga/"} {
+		if node, _ := r.getRoute("GET", path); node != nil {
+			t.Fatalf("%s shouldn't match, got %s", path, node.pattern)
+		}
+	}
+}
+
+func TestGetRouteZeroAllocs(t *testing.T) {
+	r := newTestRouter()
+	for _, path := range []string{"/hello/b/c", "/hello/makabaka"} {
+		allocs := testing.AllocsPerRun(100, func() {
+			_, params := r.getRoute("GET", path)
+			r.putParams(params)
+		})
+		if allocs != 0 {
+			t.Fatalf("getRoute(%s) should not allocate, got %v allocs", path, allocs)
+		}
+	}
+}
+
+func BenchmarkGetRouteStatic(b *testing.B) {
+	r := newTestRouter()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, params := r.getRoute("GET", "/hello/b/c")
+		r.putParams(params)
+	}
+}
+
+func BenchmarkGetRouteParam(b *testing.B) {
+	r := newTestRouter()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, params := r.getRoute("GET", "/hello/makabaka")
+		r.putParams(params)
+	}
+}
+
+func BenchmarkGetRouteCatchAll(b *testing.B) {
+	r := newTestRouter()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, params := r.getRoute("GET", "/assets/css/giga/a.css")
+		r.putParams(params)
+	}
+}
+
+func TestParamConstraints(t *testing.T) {
+	r := newRouter()
+	r.addRoute("GET", "/file/:name", nil)
+	r.addRoute("GET", "/file/:id<int>", nil)
+	r.addRoute("GET", "/file/:uuid<uuid>", nil)
+	r.addRoute("GET", "/post/:slug{[a-z-]+}", nil)
+
+	cases := map[string]string{
+		"/file/42": "/file/:id<int>",
+		"/file/-7": "/file/:id<int>",
+		"/file/123e4567-e89b-12d3-a456-426614174000": "/file/:uuid<uuid>",
+		"/file/readme":     "/file/:name",
+		"/post/hello-giga": "/post/:slug{[a-z-]+}",
+	}
+	for path, pattern := range cases {
+		node, _ := r.getRoute("GET", path)
+		if node == nil || node.pattern != pattern {
+			t.Fatalf("%s should match %s, got %+v", path, pattern, node)
+		}
+	}
+	if node, _ := r.getRoute("GET", "/post/Hello"); node != nil {
+		t.Fatalf("/post/Hello shouldn't match %s", node.pattern)
+	}
+
+	_, params := r.getRoute("GET", "/file/42")
+	if params.ByName("id") != "42" {
+		t.Fatalf("param name should strip constraint, got %v", *params)
+	}
+}
+
+func TestParamConstraintConflict(t *testing.T) {
+	for _, patterns := range [][2]string{
+		{"/file/:id<int>", "/file/:num<int>"},
+		{"/file/:id<float>", ""},
+		{"/file/:id{[0-9}", ""},
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("registering %v should panic", patterns)
+				}
+			}()
+			r := newRouter()
+			for _, pattern := range patterns {
+				if pattern != "" {
+					r.addRoute("GET", pattern, nil)
+				}
+			}
+		}()
+	}
 }
@@ -0,0 +1,57 @@
+package giga
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"runtime"
+	"text/tabwriter"
+)
+
+// RouteInfo 路由信息，用于查看已注册的路由
+type RouteInfo struct {
+	Method      string
+	Pattern     string // 包含分组前缀的完整路由
+	Name        string // 路由名称，未命名时为空
+	Handler     string // 处理函数名，例如 apiProxy/internal/handler/user.(*HandlerUser).UserLogin-fm
+	Prefix      string // 所属分组的前缀
+	Host        string // 绑定的host，未绑定时为空
+	Middlewares int    // 作用于该路由的中间件数量，包括分组及路由级别的中间件
+}
+
+// Routes 按注册顺序返回全部路由信息
+func (engine *Engine) Routes() []RouteInfo {
+	routes := make([]RouteInfo, 0, len(engine.routes))
+	for _, route := range engine.routes {
+		routes = append(routes, RouteInfo{
+			Method:      route.Method,
+			Pattern:     route.Pattern,
+			Name:        route.name,
+			Handler:     nameOfFunction(route.handlers[len(route.handlers)-1]),
+			Prefix:      route.group.prefix,
+			Host:        route.group.host,
+			Middlewares: len(route.handlers) - 1,
+		})
+	}
+	return routes
+}
+
+// PrintRoutes 以表格形式输出路由表
+func (engine *Engine) PrintRoutes(w io.Writer) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "METHOD\tHOST\tPATTERN\tNAME\tHANDLER\tPREFIX\tMIDDLEWARES")
+	for _, route := range engine.Routes() {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%d\n", route.Method, route.Host, route.Pattern,
+			route.Name, route.Handler, route.Prefix, route.Middlewares)
+	}
+	tw.Flush()
+}
+
+// nameOfFunction 通过反射获取函数名
+func nameOfFunction(f interface{}) string {
+	v := reflect.ValueOf(f)
+	if v.Kind() != reflect.Func || v.IsNil() {
+		return ""
+	}
+	return runtime.FuncForPC(v.Pointer()).Name()
+}
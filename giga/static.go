@@ -0,0 +1,167 @@
+package giga
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"html"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// StaticConfig 静态文件服务的配置
+type StaticConfig struct {
+	Browse bool          // 目录下没有Index文件时，是否列出目录内容，默认返回404
+	Index  string        // 目录的默认文件，默认为index.html
+	SPA    bool          // 文件不存在时返回根目录的Index文件，用于单页应用的前端路由
+	MaxAge time.Duration // 设置Cache-Control的max-age，为0时不设置
+}
+
+// Static 将本地目录dir挂载到prefix下，例如 r.Static("/assets", "./public")
+func (group *RouterGroup) Static(prefix string, dir string, config ...StaticConfig) {
+	group.StaticFS(prefix, http.Dir(dir), config...)
+}
+
+// StaticFSys 将fs.FS挂载到prefix下，例如 r.StaticFSys("/assets", assets)，assets为embed.FS
+func (group *RouterGroup) StaticFSys(prefix string, fsys fs.FS, config ...StaticConfig) {
+	group.StaticFS(prefix, http.FS(fsys), config...)
+}
+
+// StaticFS 将文件系统挂载到prefix下，fs.FS可使用StaticFSys或通过http.FS转换
+func (group *RouterGroup) StaticFS(prefix string, fsys http.FileSystem, config ...StaticConfig) {
+	conf := StaticConfig{}
+	if len(config) > 0 {
+		conf = config[0]
+	}
+	if conf.Index == "" {
+		conf.Index = "index.html"
+	}
+
+	etags := new(sync.Map)
+	handler := func(c *Context) {
+		// 以/为根清理路径，去除 .. 等，防止访问到目录之外的文件
+		name := path.Clean("/" + c.Param("filepath"))
+		serveStatic(c, fsys, name, conf, etags)
+	}
+	prefix = strings.TrimSuffix(prefix, "/")
+	group.GET(prefix, handler)
+	group.GET(prefix+"/*filepath", handler)
+}
+
+// StaticFile 将单个本地文件挂载到path，例如 r.StaticFile("/favicon.ico", "./public/favicon.ico")
+func (group *RouterGroup) StaticFile(path string, file string) {
+	fsys := http.Dir(filepath.Dir(file))
+	name := "/" + filepath.Base(file)
+	etags := new(sync.Map)
+	group.GET(path, func(c *Context) {
+		serveStatic(c, fsys, name, StaticConfig{}, etags)
+	})
+}
+
+// serveStatic 输出文件，由http.ServeContent处理Range、If-Modified-Since及If-None-Match，
+// 文件不存在时与路由不存在相同，使用Engine.NoRoute设置的处理函数
+func serveStatic(c *Context, fsys http.FileSystem, name string, conf StaticConfig, etags *sync.Map) {
+	f, err := fsys.Open(name)
+	if err != nil && conf.SPA {
+		name = "/" + conf.Index
+		f, err = fsys.Open(name)
+	}
+	if err != nil {
+		c.notFound()
+		return
+	}
+	defer f.Close()
+
+	stat, err := f.Stat()
+	if err != nil {
+		c.String(http.StatusInternalServerError, "500 INTERNAL SERVER ERROR: %s\n", err)
+		return
+	}
+	if stat.IsDir() {
+		index, err := fsys.Open(path.Join(name, conf.Index))
+		if err != nil {
+			if conf.Browse {
+				listDir(c, f)
+			} else {
+				c.notFound()
+			}
+			return
+		}
+		defer index.Close()
+		indexStat, err := index.Stat()
+		if err != nil || indexStat.IsDir() {
+			c.notFound()
+			return
+		}
+		f, stat, name = index, indexStat, path.Join(name, conf.Index)
+	}
+
+	if c.Writer.Header().Get("ETag") == "" {
+		etag, err := staticETag(f, name, stat, etags)
+		if err != nil {
+			c.String(http.StatusInternalServerError, "500 INTERNAL SERVER ERROR: %s\n", err)
+			return
+		}
+		c.SetHeader("ETag", etag)
+	}
+	if conf.MaxAge > 0 {
+		c.SetHeader("Cache-Control", fmt.Sprintf("public, max-age=%d", int(conf.MaxAge.Seconds())))
+	}
+	http.ServeContent(c.Writer, c.Req, stat.Name(), stat.ModTime(), f)
+}
+
+// staticETag 由修改时间及大小生成ETag，embed.FS等没有修改时间的文件使用内容的摘要，
+// 否则不同版本中大小相同的文件ETag相同，客户端会一直使用缓存的旧文件。
+// 摘要按文件名、大小缓存在etags中，文件内容变化时需重启服务
+func staticETag(f http.File, name string, stat fs.FileInfo, etags *sync.Map) (string, error) {
+	if !stat.ModTime().IsZero() {
+		return fmt.Sprintf(`"%x-%x"`, stat.ModTime().UnixNano(), stat.Size()), nil
+	}
+	key := fmt.Sprintf("%s:%d", name, stat.Size())
+	if etag, ok := etags.Load(key); ok {
+		return etag.(string), nil
+	}
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	etag := `"` + hex.EncodeToString(h.Sum(nil)[:16]) + `"`
+	etags.Store(key, etag)
+	return etag, nil
+}
+
+// listDir 列出目录内容，链接使用绝对路径，因此请求路径是否以/结尾都能正确跳转
+func listDir(c *Context, dir http.File) {
+	files, err := dir.Readdir(-1)
+	if err != nil {
+		c.String(http.StatusInternalServerError, "500 INTERNAL SERVER ERROR: %s\n", err)
+		return
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Name() < files[j].Name() })
+
+	base := strings.TrimSuffix(c.Req.URL.Path, "/") + "/"
+	var sb strings.Builder
+	sb.WriteString("<pre>\n")
+	for _, file := range files {
+		name := file.Name()
+		if file.IsDir() {
+			name += "/"
+		}
+		u := url.URL{Path: base + name}
+		fmt.Fprintf(&sb, "<a href=\"%s\">%s</a>\n", u.String(), html.EscapeString(name))
+	}
+	sb.WriteString("</pre>\n")
+	c.SetHeader("Content-Type", "text/html; charset=utf-8")
+	c.Data(http.StatusOK, []byte(sb.String()))
+}
@@ -0,0 +1,149 @@
+package giga
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func newStaticDir(t *testing.T) string {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "css"), 0755)
+	os.MkdirAll(filepath.Join(dir, "docs"), 0755)
+	os.WriteFile(filepath.Join(dir, "index.html"), []byte("<h1>index</h1>"), 0644)
+	os.WriteFile(filepath.Join(dir, "css", "a.css"), []byte("body{color:red}"), 0644)
+	os.WriteFile(filepath.Join(dir, "docs", "readme.txt"), []byte("readme"), 0644)
+	return dir
+}
+
+func TestStatic(t *testing.T) {
+	dir := newStaticDir(t)
+	r := NewEngine()
+	r.Static("/assets", dir)
+	r.Static("/browse", dir, StaticConfig{Browse: true})
+	r.StaticFile("/favicon.css", filepath.Join(dir, "css", "a.css"))
+
+	cases := []struct {
+		path string
+		code int
+		body string
+	}{
+		{"/assets/css/a.css", http.StatusOK, "body{color:red}"},
+		{"/assets", http.StatusOK, "<h1>index</h1>"},
+		{"/assets/docs", http.StatusNotFound, ""},
+		{"/assets/../static_test.go", http.StatusNotFound, ""},
+		{"/assets/missing.js", http.StatusNotFound, ""},
+		{"/favicon.css", http.StatusOK, "body{color:red}"},
+	}
+	for _, tc := range cases {
+		w := performRequest(r, "GET", tc.path)
+		if w.Code != tc.code || (tc.body != "" && w.Body.String() != tc.body) {
+			t.Fatalf("%s: expect %d %q, got %d %q", tc.path, tc.code, tc.body, w.Code, w.Body.String())
+		}
+	}
+
+	w := performRequest(r, "GET", "/browse/docs")
+	if !strings.Contains(w.Body.String(), `<a href="/browse/docs/readme.txt">readme.txt</a>`) {
+		t.Fatalf("unexpected directory listing %q", w.Body.String())
+	}
+}
+
+func TestStaticCaching(t *testing.T) {
+	r := NewEngine()
+	r.Static("/assets", newStaticDir(t), StaticConfig{MaxAge: time.Hour})
+
+	w := performRequest(r, "GET", "/assets/css/a.css")
+	etag := w.Header().Get("ETag")
+	if etag == "" || w.Header().Get("Last-Modified") == "" {
+		t.Fatal("ETag and Last-Modified should be set")
+	}
+	if w.Header().Get("Cache-Control") != "public, max-age=3600" {
+		t.Fatalf("unexpected Cache-Control %q", w.Header().Get("Cache-Control"))
+	}
+
+	req := httptest.NewRequest("GET", "/assets/css/a.css", nil)
+	req.Header.Set("If-None-Match", etag)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusNotModified {
+		t.Fatalf("If-None-Match should return 304, got %d", w.Code)
+	}
+
+	req = httptest.NewRequest("GET", "/assets/css/a.css", nil)
+	req.Header.Set("Range", "bytes=0-3")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusPartialContent || w.Body.String() != "body" {
+		t.Fatalf("Range should return 206 body, got %d %q", w.Code, w.Body.String())
+	}
+}
+
+func TestStaticFSysETag(t *testing.T) {
+	// 没有修改时间的文件，大小相同、内容不同时ETag也不同
+	v1 := NewEngine()
+	v1.StaticFSys("/assets", fstest.MapFS{"app.js": {Data: []byte("v1();")}})
+	v2 := NewEngine()
+	v2.StaticFSys("/assets", fstest.MapFS{"app.js": {Data: []byte("v2();")}})
+
+	etag := performRequest(v1, "GET", "/assets/app.js").Header().Get("ETag")
+	if etag == "" || etag == performRequest(v2, "GET", "/assets/app.js").Header().Get("ETag") {
+		t.Fatalf("files with different content should have different ETag, got %q", etag)
+	}
+
+	req := httptest.NewRequest("GET", "/assets/app.js", nil)
+	req.Header.Set("If-None-Match", etag)
+	w := httptest.NewRecorder()
+	v2.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || w.Body.String() != "v2();" {
+		t.Fatalf("stale ETag should return new content, got %d %q", w.Code, w.Body.String())
+	}
+	w = httptest.NewRecorder()
+	v1.ServeHTTP(w, req)
+	if w.Code != http.StatusNotModified {
+		t.Fatalf("If-None-Match should return 304, got %d", w.Code)
+	}
+}
+
+func TestStaticFSWithSPA(t *testing.T) {
+	fsys := fstest.MapFS{
+		"index.html":  {Data: []byte("spa")},
+		"js/app.js":   {Data: []byte("app")},
+		"js/lib/x.js": {Data: []byte("x")},
+	}
+	r := NewEngine()
+	r.GET("/api/user", func(c *Context) { c.String(http.StatusOK, "api") })
+	r.StaticFSys("/", fsys, StaticConfig{SPA: true})
+
+	cases := map[string]string{
+		"/":             "spa",
+		"/js/app.js":    "app",
+		"/user/42/edit": "spa",
+		"/api/user":     "api",
+	}
+	for path, expect := range cases {
+		if w := performRequest(r, "GET", path); w.Body.String() != expect {
+			t.Fatalf("%s: expect %q, got %d %q", path, expect, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestStaticNoRoute(t *testing.T) {
+	r := NewEngine()
+	r.NoRoute(func(c *Context) {
+		c.JSON(http.StatusNotFound, H{"message": "not found"})
+	})
+	r.StaticFSys("/assets", fstest.MapFS{"a.css": {Data: []byte("a")}})
+
+	// 静态文件不存在时与路由不存在的响应一致
+	for _, path := range []string{"/assets/missing.css", "/missing"} {
+		w := performRequest(r, "GET", path)
+		if w.Code != http.StatusNotFound || w.Body.String() != "{\"message\":\"not found\"}\n" {
+			t.Fatalf("%s: unexpected code %d, body %q", path, w.Code, w.Body.String())
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package giga
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// ServerSentEvent 服务端推送的事件，Data为string或[]byte时原样输出，其余类型输出为json，
+// Retry为客户端断线后重连的间隔，为0时不设置
+type ServerSentEvent struct {
+	ID    string
+	Event string
+	Data  interface{}
+	Retry time.Duration
+}
+
+// Stream 循环调用step写入响应，每次调用后flush，step返回false或客户端断开连接时结束，
+// 返回值表示客户端是否已断开，例如
+//
+//	c.Stream(func(w io.Writer) bool {
+//		progress, ok := <-ch
+//		if ok {
+//			fmt.Fprintf(w, "%d%%\n", progress)
+//		}
+//		return ok
+//	})
+func (c *Context) Stream(step func(w io.Writer) bool) bool {
+	done := c.Req.Context().Done()
+	for {
+		select {
+		case <-done:
+			return true
+		default:
+		}
+		keepOpen := step(c.Writer)
+		c.Writer.Flush()
+		if !keepOpen {
+			return false
+		}
+	}
+}
+
+// SSEvent 推送名为name的事件并flush
+func (c *Context) SSEvent(name string, data interface{}) error {
+	return c.WriteSSE(ServerSentEvent{Event: name, Data: data})
+}
+
+// WriteSSE 推送事件并flush，第一次推送前设置text/event-stream等响应头
+func (c *Context) WriteSSE(event ServerSentEvent) error {
+	c.sseHeaders()
+	var sb strings.Builder
+	if event.ID != "" {
+		sb.WriteString("id: " + sseEscape(event.ID) + "\n")
+	}
+	if event.Event != "" {
+		sb.WriteString("event: " + sseEscape(event.Event) + "\n")
+	}
+	if event.Retry > 0 {
+		fmt.Fprintf(&sb, "retry: %d\n", event.Retry.Milliseconds())
+	}
+
+	var data string
+	switch v := event.Data.(type) {
+	case nil:
+	case string:
+		data = v
+	case []byte:
+		data = string(v)
+	default:
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		data = string(encoded)
+	}
+	// 多行数据每行一个data字段，客户端会以\n拼接
+	for _, line := range strings.Split(strings.ReplaceAll(data, "\r\n", "\n"), "\n") {
+		sb.WriteString("data: " + line + "\n")
+	}
+	sb.WriteString("\n")
+	return c.writeSSE(sb.String())
+}
+
+// SSEComment 推送注释，客户端会忽略，可用于心跳保持连接
+func (c *Context) SSEComment(comment string) error {
+	c.sseHeaders()
+	return c.writeSSE(": " + sseEscape(comment) + "\n\n")
+}
+
+// SSEStream 推送events中的事件，events关闭或客户端断开连接时结束，返回值表示客户端是否已断开，
+// heartbeat大于0时，每隔heartbeat没有事件就推送一次心跳注释，防止代理等因连接空闲而断开
+func (c *Context) SSEStream(events <-chan ServerSentEvent, heartbeat time.Duration) bool {
+	c.sseHeaders()
+	c.Writer.Flush()
+
+	var tick <-chan time.Time
+	if heartbeat > 0 {
+		ticker := time.NewTicker(heartbeat)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	done := c.Req.Context().Done()
+	for {
+		select {
+		case <-done:
+			return true
+		case event, ok := <-events:
+			if !ok {
+				return false
+			}
+			if err := c.WriteSSE(event); err != nil {
+				return true
+			}
+		case <-tick:
+			if err := c.SSEComment("ping"); err != nil {
+				return true
+			}
+		}
+	}
+}
+
+func (c *Context) sseHeaders() {
+	if c.Writer.Written() {
+		return
+	}
+	header := c.Writer.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	// 关闭nginx的响应缓冲
+	header.Set("X-Accel-Buffering", "no")
+}
+
+func (c *Context) writeSSE(s string) error {
+	if _, err := c.Writer.WriteString(s); err != nil {
+		return err
+	}
+	c.Writer.Flush()
+	return nil
+}
+
+// sseEscape 去除换行符，防止单行字段被拆分为多个字段
+func sseEscape(s string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(s)
+}
@@ -0,0 +1,119 @@
+package giga
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStream(t *testing.T) {
+	r := NewEngine()
+	r.GET("/progress", func(c *Context) {
+		i := 0
+		c.Stream(func(w io.Writer) bool {
+			i++
+			fmt.Fprintf(w, "%d\n", i*25)
+			return i < 4
+		})
+	})
+	w := performRequest(r, "GET", "/progress")
+	if !w.Flushed || w.Body.String() != "25\n50\n75\n100\n" {
+		t.Fatalf("unexpected body %q", w.Body.String())
+	}
+
+	// 客户端断开后不再调用step
+	ctx, cancel := context.WithCancel(context.Background())
+	calls := 0
+	r.GET("/forever", func(c *Context) {
+		if !c.Stream(func(w io.Writer) bool {
+			calls++
+			cancel()
+			return true
+		}) {
+			t.Error("stream should report client disconnect")
+		}
+	})
+	req := httptest.NewRequest("GET", "/forever", nil).WithContext(ctx)
+	r.ServeHTTP(httptest.NewRecorder(), req)
+	if calls != 1 {
+		t.Fatalf("unexpected calls %d", calls)
+	}
+}
+
+func TestSSEvent(t *testing.T) {
+	r := NewEngine()
+	r.GET("/events", func(c *Context) {
+		c.SSEvent("status", "sent\nok")
+		c.WriteSSE(ServerSentEvent{ID: "2", Event: "job", Data: H{"progress": 50}, Retry: 3 * time.Second})
+	})
+	w := performRequest(r, "GET", "/events")
+	expected := "event: status\ndata: sent\ndata: ok\n\nid: 2\nevent: job\nretry: 3000\ndata: {\"progress\":50}\n\n"
+	if w.Body.String() != expected {
+		t.Fatalf("unexpected body %q", w.Body.String())
+	}
+	if w.Header().Get("Content-Type") != "text/event-stream" || w.Header().Get("Cache-Control") != "no-cache" {
+		t.Fatalf("unexpected headers %v", w.Header())
+	}
+}
+
+func TestSSEStream(t *testing.T) {
+	events := make(chan ServerSentEvent)
+	disconnected := make(chan bool, 1)
+	r := NewEngine()
+	r.GET("/events", func(c *Context) {
+		disconnected <- c.SSEStream(events, 10*time.Millisecond)
+	})
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, "GET", srv.URL+"/events", nil)
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	reader := bufio.NewReader(res.Body)
+	readEvent := func() string {
+		var lines []string
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				t.Fatal(err)
+			}
+			if line == "\n" {
+				return strings.Join(lines, "")
+			}
+			lines = append(lines, line)
+		}
+	}
+
+	// 没有事件时推送心跳
+	if event := readEvent(); event != ": ping\n" {
+		t.Fatalf("unexpected heartbeat %q", event)
+	}
+	events <- ServerSentEvent{Event: "captcha", Data: "delivered"}
+	for event := readEvent(); event != "event: captcha\ndata: delivered\n"; event = readEvent() {
+		if event != ": ping\n" {
+			t.Fatalf("unexpected event %q", event)
+		}
+	}
+
+	// 客户端断开后SSEStream返回
+	cancel()
+	select {
+	case ok := <-disconnected:
+		if !ok {
+			t.Fatal("SSEStream should report client disconnect")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("SSEStream did not return after client disconnect")
+	}
+}
@@ -1,68 +1,335 @@
 package giga
 
-import "strings"
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+)
 
+type nodeType uint8
+
+const (
+	static   nodeType = iota // 静态节点，例如 /hello/
+	param                    // 参数节点，例如 :name
+	catchAll                 // 通配节点，例如 *filepath
+)
+
+// node 压缩前缀树(radix tree)的节点
+// 静态路径按公共前缀压缩存储，子节点通过首字节索引查找；参数及通配节点只出现在路径段的开头
 type node struct {
-	pattern  string  // http请求路径，例如 /index/:id/detail
-	part     string  // 路由中的一部分，例如 :id
-	children []*node // 子节点
-	isWild   bool    // 是否精确匹配，part 含有 : 或 * 时为true
+	path     string   // 静态节点为压缩后的路径片段，例如 /hello/；参数节点为 :name；通配节点为 *filepath
+	nType    nodeType // 节点类型
+	indices  string   // 静态子节点path的首字节，与children一一对应
+	children []*node  // 静态子节点
+	// 参数及通配子节点，匹配优先级：静态 > :参数 > *通配
+	// 同一位置可以存在多个约束不同的参数节点，带约束的在前，无约束的在最后
+	paramChildren []*node
+	catchAllChild *node
+	constraint    func(string) bool // 参数节点的约束，例如 :id<int>，为空时匹配任意非空路径段
+
+	// 只有叶子节点才设置
+	pattern     string        // 完整的路由，例如 /index/:id/detail
+	paramNames  []string      // 路由中的参数名，按出现顺序排列，查找时据此填充参数
+	handlers    []HandlerFunc // 注册时绑定的完整处理链，包括各级中间件及请求处理函数
+	hasHandlers bool          // 是否已注册不带请求头条件的处理链
+	// 带请求头条件的处理链，按注册顺序匹配，优先于handlers
+	headerRoutes []*headerRoute
 }
 
-// 第一个匹配成功的节点，用于插入
-func (n *node) matchChild(part string) *node {
-	for _, child := range n.children {
-		if child.part == part || child.isWild {
-			return child
+// headerRoute 带请求头条件的处理链
+type headerRoute struct {
+	headers  []headerMatcher
+	handlers []HandlerFunc
+}
+
+// addHandlers 为叶子节点设置处理链，相同条件重复注册时panic
+func (n *node) addHandlers(handlers []HandlerFunc, headers []headerMatcher) {
+	if len(headers) == 0 {
+		if n.hasHandlers {
+			panic(fmt.Sprintf("giga: route '%s' conflicts with existing route", n.pattern))
 		}
+		n.handlers, n.hasHandlers = handlers, true
+		return
 	}
-	return nil
+
+	key := headersKey(headers)
+	for _, route := range n.headerRoutes {
+		if headersKey(route.headers) == key {
+			panic(fmt.Sprintf("giga: route '%s' with headers %s conflicts with existing route", n.pattern, key))
+		}
+	}
+	n.headerRoutes = append(n.headerRoutes, &headerRoute{headers: headers, handlers: handlers})
 }
 
-// 所有匹配成功的节点，用于查找
-func (n *node) matchChildren(part string) []*node {
-	nodes := make([]*node, 0)
-	for _, child := range n.children {
-		if child.part == part || child.isWild {
-			nodes = append(nodes, child)
+// handlersFor 返回与请求头匹配的处理链，均不匹配时返回nil
+func (n *node) handlersFor(req *http.Request) []HandlerFunc {
+	for _, route := range n.headerRoutes {
+		if matchHeaders(route.headers, req) {
+			return route.handlers
 		}
 	}
-	return nodes
+	return n.handlers
 }
 
-func (n *node) insert(pattern string, parts []string, height int) {
-	// 只有叶子节点 pattern才设置
-	if len(parts) == height {
-		n.pattern = pattern
-		return
+// 两个字符串的公共前缀长度
+func longestCommonPrefix(a, b string) int {
+	i := 0
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i++
+	}
+	return i
+}
+
+// addStatic 在n之下插入静态片段path，必要时分裂已有节点，返回path末尾对应的节点
+func (n *node) addStatic(path string) *node {
+	for path != "" {
+		i := strings.IndexByte(n.indices, path[0])
+		if i < 0 {
+			child := &node{path: path, nType: static}
+			n.indices += string(path[0])
+			n.children = append(n.children, child)
+			return child
+		}
+
+		child := n.children[i]
+		l := longestCommonPrefix(path, child.path)
+		if l < len(child.path) {
+			// 分裂节点，例如已有 /hello/，插入 /hi 时分裂为 /h -> ello/
+			split := *child
+			split.path = child.path[l:]
+			*child = node{
+				path:     child.path[:l],
+				nType:    static,
+				indices:  string(split.path[0]),
+				children: []*node{&split},
+			}
+		}
+		n = child
+		path = path[l:]
+	}
+	return n
+}
+
+// addWild 在n之下插入参数或通配节点part。
+// 同一位置约束相同但名称不同的参数节点视为冲突，例如 :id 与 :name、:id<int> 与 :num<int>
+func (n *node) addWild(part string, pattern string) *node {
+	name, constraint := splitParam(part)
+	if name == "" && part[0] == ':' {
+		panic(fmt.Sprintf("giga: wildcard '%s' must be named in route '%s'", part, pattern))
+	}
+
+	if part[0] == '*' {
+		if n.catchAllChild == nil {
+			n.catchAllChild = &node{path: part, nType: catchAll}
+		} else if n.catchAllChild.path != part {
+			panic(fmt.Sprintf("giga: wildcard '%s' in route '%s' conflicts with existing wildcard '%s'",
+				part, pattern, n.catchAllChild.path))
+		}
+		return n.catchAllChild
+	}
+
+	for _, child := range n.paramChildren {
+		if child.path == part {
+			return child
+		}
+		if _, c := splitParam(child.path); c == constraint {
+			panic(fmt.Sprintf("giga: wildcard '%s' in route '%s' conflicts with existing wildcard '%s'",
+				part, pattern, child.path))
+		}
+	}
+	match, err := compileConstraint(constraint)
+	if err != nil {
+		panic(fmt.Sprintf("giga: invalid constraint in route '%s': %v", pattern, err))
 	}
+	child := &node{path: part, nType: param, constraint: match}
+	// 无约束的参数节点始终排在最后，保证带约束的节点优先匹配
+	i := len(n.paramChildren)
+	if constraint != "" {
+		for i > 0 && n.paramChildren[i-1].constraint == nil {
+			i--
+		}
+	}
+	n.paramChildren = append(n.paramChildren, nil)
+	copy(n.paramChildren[i+1:], n.paramChildren[i:])
+	n.paramChildren[i] = child
+	return child
+}
 
-	part := parts[height]
-	child := n.matchChild(part)
-	if child == nil {
-		child = &node{part: part, isWild: part[0] == ':' || part[0] == '*'}
-		n.children = append(n.children, child)
+// splitParam 拆分参数名及约束，例如 :id<int> => id, <int>；:slug{[a-z-]+} => slug, {[a-z-]+}
+func splitParam(part string) (name string, constraint string) {
+	name = part[1:]
+	if part[0] != ':' {
+		return name, ""
 	}
-	child.insert(pattern, parts, height+1)
+	if i := strings.IndexAny(name, "<{"); i >= 0 {
+		return name[:i], name[i:]
+	}
+	return name, ""
 }
 
-func (n *node) search(parts []string, height int) *node {
-	if len(parts) == height || strings.HasPrefix(n.part, "*") {
+// search 匹配剩余路径path，n自身的path已匹配。
+// 依次尝试静态子节点、参数子节点、通配子节点，失败时回溯；参数值按出现顺序追加到ps中
+func (n *node) search(path string, ps *Params) *node {
+	if path == "" {
 		if n.pattern == "" {
 			return nil
 		}
 		return n
 	}
 
-	part := parts[height]
-	children := n.matchChildren(part)
+	if i := strings.IndexByte(n.indices, path[0]); i >= 0 {
+		child := n.children[i]
+		if strings.HasPrefix(path, child.path) {
+			if result := child.search(path[len(child.path):], ps); result != nil {
+				return result
+			}
+		}
+	}
+
+	if len(n.paramChildren) > 0 {
+		end := strings.IndexByte(path, '/')
+		if end < 0 {
+			end = len(path)
+		}
+		// 参数不能为空，例如 /hello/ 不匹配 /hello/:name
+		for _, child := range n.paramChildren {
+			if end == 0 || (child.constraint != nil && !child.constraint(path[:end])) {
+				continue
+			}
+			*ps = append(*ps, Param{Value: path[:end]})
+			if result := child.search(path[end:], ps); result != nil {
+				return result
+			}
+			*ps = (*ps)[:len(*ps)-1]
+		}
+	}
+
+	// 通配节点匹配剩余的全部路径，例如 /assets/css/a.css 匹配 /assets/*filepath，值为 css/a.css
+	if child := n.catchAllChild; child != nil && child.pattern != "" {
+		*ps = append(*ps, Param{Value: path})
+		return child
+	}
+
+	return nil
+}
+
+// searchFold 忽略大小写匹配剩余路径path，匹配成功时返回按已注册路由的大小写修正后的完整路径
+func (n *node) searchFold(path string, buf []byte) []byte {
+	if path == "" {
+		if n.pattern == "" {
+			return nil
+		}
+		return buf
+	}
+
+	for _, child := range n.children {
+		if len(path) >= len(child.path) && strings.EqualFold(path[:len(child.path)], child.path) {
+			if result := child.searchFold(path[len(child.path):], append(buf, child.path...)); result != nil {
+				return result
+			}
+		}
+	}
 
-	for _, child := range children {
-		result := child.search(parts, height+1)
-		if result != nil {
+	end := strings.IndexByte(path, '/')
+	if end < 0 {
+		end = len(path)
+	}
+	for _, child := range n.paramChildren {
+		if end == 0 || (child.constraint != nil && !child.constraint(path[:end])) {
+			continue
+		}
+		if result := child.searchFold(path[end:], append(buf, path[:end]...)); result != nil {
 			return result
 		}
 	}
 
+	if child := n.catchAllChild; child != nil && child.pattern != "" {
+		return append(buf, path...)
+	}
 	return nil
 }
+
+// paramTypes 内置的参数类型约束，例如 :id<int>
+var paramTypes = map[string]func(string) bool{
+	"int":   isInt,
+	"uint":  isDigits,
+	"alpha": isAlpha,
+	"alnum": isAlnum,
+	"uuid":  isUUID,
+}
+
+// compileConstraint 解析参数约束，<type>为内置类型，{regexp}为正则表达式，需匹配整个路径段
+func compileConstraint(constraint string) (func(string) bool, error) {
+	if constraint == "" {
+		return nil, nil
+	}
+	last := constraint[len(constraint)-1]
+	switch {
+	case constraint[0] == '<' && last == '>':
+		match, ok := paramTypes[constraint[1:len(constraint)-1]]
+		if !ok {
+			return nil, fmt.Errorf("unknown param type %s", constraint)
+		}
+		return match, nil
+	case constraint[0] == '{' && last == '}':
+		re, err := regexp.Compile("^(?:" + constraint[1:len(constraint)-1] + ")$")
+		if err != nil {
+			return nil, err
+		}
+		return re.MatchString, nil
+	}
+	return nil, fmt.Errorf("malformed constraint %s", constraint)
+}
+
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+func isInt(s string) bool {
+	if s != "" && (s[0] == '-' || s[0] == '+') {
+		s = s[1:]
+	}
+	return isDigits(s)
+}
+
+func isAlpha(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if (s[i] < 'a' || s[i] > 'z') && (s[i] < 'A' || s[i] > 'Z') {
+			return false
+		}
+	}
+	return s != ""
+}
+
+func isAlnum(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if !isAlpha(s[i:i+1]) && !isDigits(s[i:i+1]) {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// isUUID 校验形如 123e4567-e89b-12d3-a456-426614174000 的uuid
+func isUUID(s string) bool {
+	if len(s) != 36 {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		switch {
+		case i == 8 || i == 13 || i == 18 || i == 23:
+			if s[i] != '-' {
+				return false
+			}
+		case (s[i] < '0' || s[i] > '9') && (s[i] < 'a' || s[i] > 'f') && (s[i] < 'A' || s[i] > 'F'):
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,83 @@
+package giga
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Route 已注册的路由，可通过Name为路由命名，再使用Engine.URL反向生成url
+// 例如 r.GET("/user/:id", h).Name("user.detail")
+type Route struct {
+	Method  string
+	Pattern string // 包含分组前缀的完整路由，例如 /user/:id
+
+	name     string
+	group    *RouterGroup
+	handlers []HandlerFunc // 完整的处理链，最后一个为请求处理函数
+}
+
+// Name 为路由命名，名称在engine内需唯一，重复时panic
+func (route *Route) Name(name string) *Route {
+	engine := route.group.engine
+	if exist, ok := engine.namedRoutes[name]; ok {
+		panic(fmt.Sprintf("giga: route name '%s' of '%s %s' is already used by '%s %s'",
+			name, route.Method, route.Pattern, exist.Method, exist.Pattern))
+	}
+	route.name = name
+	engine.namedRoutes[name] = route
+	return route
+}
+
+// URL 根据路由名称及参数生成url，参数按 key, value 成对传入，
+// 例如 engine.URL("user.detail", "id", "42") => /user/42
+func (engine *Engine) URL(name string, pairs ...string) (string, error) {
+	if len(pairs)%2 != 0 {
+		return "", fmt.Errorf("giga: url params of route '%s' must be key-value pairs", name)
+	}
+	params := make(map[string]string, len(pairs)/2)
+	for i := 0; i < len(pairs); i += 2 {
+		params[pairs[i]] = pairs[i+1]
+	}
+	return engine.URLMap(name, params)
+}
+
+// URLMap 根据路由名称及参数生成url，缺少:参数或*通配参数、参数不满足约束时返回error
+func (engine *Engine) URLMap(name string, params map[string]string) (string, error) {
+	route, ok := engine.namedRoutes[name]
+	if !ok {
+		return "", fmt.Errorf("giga: route '%s' not found", name)
+	}
+
+	var sb strings.Builder
+	for _, part := range parsePattern(route.Pattern) {
+		sb.WriteByte('/')
+		if part[0] != ':' && part[0] != '*' {
+			sb.WriteString(part)
+			continue
+		}
+
+		key, constraint := splitParam(part)
+		value, ok := params[key]
+		if !ok || value == "" {
+			return "", fmt.Errorf("giga: missing param '%s' for route '%s' (%s)", key, name, route.Pattern)
+		}
+		if part[0] == '*' {
+			// 通配参数可以包含多级路径，逐段转义
+			segments := strings.Split(strings.TrimPrefix(value, "/"), "/")
+			for i, segment := range segments {
+				segments[i] = url.PathEscape(segment)
+			}
+			sb.WriteString(strings.Join(segments, "/"))
+			continue
+		}
+		if match, _ := compileConstraint(constraint); match != nil && !match(value) {
+			return "", fmt.Errorf("giga: param '%s' of route '%s' doesn't match %s", key, name, constraint)
+		}
+		sb.WriteString(url.PathEscape(value))
+	}
+	if sb.Len() == 0 {
+		return "/", nil
+	}
+	return sb.String(), nil
+}
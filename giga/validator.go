@@ -0,0 +1,220 @@
+package giga
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// FieldError 字段校验失败的详细信息
+type FieldError struct {
+	Field   string `json:"field"`           // 字段名，优先使用json或form tag中的名称，嵌套字段形如 address.city
+	Rule    string `json:"rule"`            // 未通过的规则，例如 required、min，类型转换失败时为type
+	Param   string `json:"param,omitempty"` // 规则的参数，例如 min=18 中的 18
+	Message string `json:"message"`
+}
+
+// ValidationErrors 全部字段的校验错误
+type ValidationErrors []FieldError
+
+func (errs ValidationErrors) Error() string {
+	messages := make([]string, 0, len(errs))
+	for _, err := range errs {
+		messages = append(messages, err.Message)
+	}
+	return strings.Join(messages, "; ")
+}
+
+var (
+	emailRegexp  = regexp.MustCompile(`^[a-zA-Z0-9._%+\-]+@[a-zA-Z0-9.\-]+\.[a-zA-Z]{2,}$`)
+	mobileRegexp = regexp.MustCompile(`^1[3-9]\d{9}$`)
+	// 规则中使用的正则表达式，编译后缓存
+	ruleRegexps sync.Map
+)
+
+// validate 按binding tag校验结构体字段，支持的规则：
+// required 不能为零值；omitempty 为零值时跳过其余规则；
+// min、max、len 数字比较数值，字符串比较字符数，切片及map比较长度；
+// oneof=a b c 取值只能为其中之一；email、mobile 邮箱及手机号格式；
+// regex=^[a-z]+$ 正则匹配，因正则中可能包含逗号，需作为最后一条规则。
+// 零值同样按其余规则校验，可选字段需在其余规则之前加上omitempty
+func validate(obj interface{}) error {
+	v := reflect.ValueOf(obj)
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var fieldErrors ValidationErrors
+	validateStruct(v, "", &fieldErrors)
+	if len(fieldErrors) > 0 {
+		return fieldErrors
+	}
+	return nil
+}
+
+func validateStruct(v reflect.Value, prefix string, fieldErrors *ValidationErrors) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		fv := v.Field(i)
+		name := prefix + fieldName(field)
+		for _, rule := range splitRules(field.Tag.Get("binding")) {
+			skip, fieldError := checkRule(fv, name, rule)
+			if fieldError != nil {
+				*fieldErrors = append(*fieldErrors, *fieldError)
+			}
+			if skip {
+				break
+			}
+		}
+
+		// 递归校验嵌套结构体
+		for fv.Kind() == reflect.Ptr && !fv.IsNil() {
+			fv = fv.Elem()
+		}
+		if fv.Kind() == reflect.Struct && fv.Type() != timeType {
+			if field.Anonymous {
+				validateStruct(fv, prefix, fieldErrors)
+			} else {
+				validateStruct(fv, name+".", fieldErrors)
+			}
+		}
+	}
+}
+
+// fieldName 错误信息中的字段名，依次使用json、form、uri、header tag，均未设置时使用字段名
+func fieldName(field reflect.StructField) string {
+	for _, tag := range []string{"json", "form", "uri", "header"} {
+		if name := strings.Split(field.Tag.Get(tag), ",")[0]; name != "" && name != "-" {
+			return name
+		}
+	}
+	return field.Name
+}
+
+// splitRules 按逗号拆分规则，regex之后的内容整体作为正则表达式
+func splitRules(tag string) []string {
+	rules := make([]string, 0)
+	for tag != "" {
+		if strings.HasPrefix(tag, "regex=") {
+			return append(rules, tag)
+		}
+		rule, rest, _ := strings.Cut(tag, ",")
+		if rule = strings.TrimSpace(rule); rule != "" {
+			rules = append(rules, rule)
+		}
+		tag = rest
+	}
+	return rules
+}
+
+// checkRule 校验单条规则，skip为true时跳过该字段的其余规则，校验失败时同样跳过
+func checkRule(v reflect.Value, name string, rule string) (skip bool, fieldError *FieldError) {
+	key, param, _ := strings.Cut(rule, "=")
+	fail := func(format string, args ...interface{}) (bool, *FieldError) {
+		return true, &FieldError{Field: name, Rule: key, Param: param, Message: fmt.Sprintf(format, args...)}
+	}
+
+	zero := v.IsZero()
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			// 未设置的指针按其指向类型的零值校验
+			v = reflect.Zero(v.Type().Elem())
+			continue
+		}
+		v = v.Elem()
+	}
+	switch key {
+	case "required":
+		if zero {
+			return fail("%s is required", name)
+		}
+		return false, nil
+	case "omitempty":
+		return zero, nil
+	}
+
+	switch key {
+	case "min", "max", "len":
+		limit, parseErr := strconv.ParseFloat(param, 64)
+		if parseErr != nil {
+			panic(fmt.Sprintf("giga: invalid binding rule '%s' of field %s", rule, name))
+		}
+		size, isLength := sizeOf(v)
+		switch {
+		case key == "min" && size < limit:
+			if isLength {
+				return fail("%s must be at least %s characters or items", name, param)
+			}
+			return fail("%s must be at least %s", name, param)
+		case key == "max" && size > limit:
+			if isLength {
+				return fail("%s must be at most %s characters or items", name, param)
+			}
+			return fail("%s must be at most %s", name, param)
+		case key == "len" && size != limit:
+			return fail("%s must be exactly %s characters or items", name, param)
+		}
+	case "oneof":
+		value := fmt.Sprint(v.Interface())
+		for _, option := range strings.Fields(param) {
+			if value == option {
+				return false, nil
+			}
+		}
+		return fail("%s must be one of [%s]", name, param)
+	case "email":
+		if !emailRegexp.MatchString(fmt.Sprint(v.Interface())) {
+			return fail("%s must be a valid email", name)
+		}
+	case "mobile":
+		if !mobileRegexp.MatchString(fmt.Sprint(v.Interface())) {
+			return fail("%s must be a valid mobile number", name)
+		}
+	case "regex":
+		re, ok := ruleRegexps.Load(param)
+		if !ok {
+			compiled, compileErr := regexp.Compile(param)
+			if compileErr != nil {
+				panic(fmt.Sprintf("giga: invalid binding rule '%s' of field %s: %v", rule, name, compileErr))
+			}
+			re, _ = ruleRegexps.LoadOrStore(param, compiled)
+		}
+		if !re.(*regexp.Regexp).MatchString(fmt.Sprint(v.Interface())) {
+			return fail("%s must match %s", name, param)
+		}
+	default:
+		panic(fmt.Sprintf("giga: unknown binding rule '%s' of field %s", rule, name))
+	}
+	return false, nil
+}
+
+// sizeOf 返回用于min、max、len比较的值，字符串、切片、map返回长度，isLength为true
+func sizeOf(v reflect.Value) (size float64, isLength bool) {
+	switch v.Kind() {
+	case reflect.String:
+		return float64(utf8.RuneCountInString(v.String())), true
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return float64(v.Len()), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), false
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), false
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), false
+	}
+	return 0, false
+}
@@ -0,0 +1,516 @@
+package giga
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// websocket消息类型，对应RFC 6455中的opcode
+const (
+	TextMessage   = 1
+	BinaryMessage = 2
+	CloseMessage  = 8
+	PingMessage   = 9
+	PongMessage   = 10
+
+	continuationFrame = 0
+)
+
+// websocket关闭码，见RFC 6455 7.4.1
+const (
+	CloseNormalClosure           = 1000
+	CloseGoingAway               = 1001
+	CloseProtocolError           = 1002
+	CloseUnsupportedData         = 1003
+	CloseNoStatusReceived        = 1005
+	CloseAbnormalClosure         = 1006
+	CloseInvalidFramePayloadData = 1007
+	ClosePolicyViolation         = 1008
+	CloseMessageTooBig           = 1009
+	CloseInternalServerErr       = 1011
+)
+
+// defaultWebSocketReadLimit 单条消息的默认最大字节数
+const defaultWebSocketReadLimit = 1 << 20
+
+// maxWebSocketReadLimit 单条消息的最大字节数上限，不限制时也不能超过，防止按对方声明的长度分配过大的内存
+const maxWebSocketReadLimit = 1 << 30
+
+// websocketGUID 用于计算Sec-WebSocket-Accept
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// ErrCloseSent 已发送关闭帧后不能再发送消息
+var ErrCloseSent = errors.New("giga: websocket close sent")
+
+// CloseError 连接被关闭，Code为对方发送的关闭码，协议错误或消息过大时为本端发送的关闭码
+type CloseError struct {
+	Code int
+	Text string
+}
+
+func (e *CloseError) Error() string {
+	return fmt.Sprintf("giga: websocket closed with code %d: %s", e.Code, e.Text)
+}
+
+// IsCloseError 判断err是否为关闭码为codes之一的CloseError
+func IsCloseError(err error, codes ...int) bool {
+	var closeErr *CloseError
+	if !errors.As(err, &closeErr) {
+		return false
+	}
+	for _, code := range codes {
+		if closeErr.Code == code {
+			return true
+		}
+	}
+	return false
+}
+
+// UpgradeOptions websocket握手的配置
+type UpgradeOptions struct {
+	// ReadLimit 单条消息(包含全部分片)的最大字节数，超出时以1009关闭连接，为0时为1MiB
+	ReadLimit int64
+	// CheckOrigin 校验请求头Origin，返回false时响应403，为空时只允许Origin与Host相同或没有Origin的请求
+	CheckOrigin func(r *http.Request) bool
+	// Subprotocols 服务端支持的子协议，按优先级排列，选中客户端请求的第一个匹配项
+	Subprotocols []string
+}
+
+// Upgrade 将请求升级为websocket连接，握手失败时已写入400、403或426响应并返回error，
+// 在中间件完成鉴权等处理后调用，例如
+//
+//	r.GET("/ws", auth, func(c *giga.Context) {
+//		conn, err := c.Upgrade()
+//		if err != nil {
+//			return
+//		}
+//		defer conn.Close()
+//		for {
+//			messageType, data, err := conn.ReadMessage()
+//			if err != nil {
+//				return
+//			}
+//			conn.WriteMessage(messageType, data)
+//		}
+//	})
+func (c *Context) Upgrade(options ...UpgradeOptions) (*Conn, error) {
+	opts := UpgradeOptions{}
+	if len(options) > 0 {
+		opts = options[0]
+	}
+	if opts.ReadLimit == 0 {
+		opts.ReadLimit = defaultWebSocketReadLimit
+	}
+	if opts.CheckOrigin == nil {
+		opts.CheckOrigin = sameOrigin
+	}
+
+	fail := func(code int, reason string) (*Conn, error) {
+		c.String(code, "%d %s: %s\n", code, strings.ToUpper(http.StatusText(code)), reason)
+		return nil, errors.New("giga: websocket handshake failed: " + reason)
+	}
+	req := c.Req
+	if req.Method != "GET" {
+		return fail(http.StatusMethodNotAllowed, "method must be GET")
+	}
+	if !headerContainsToken(req.Header, "Connection", "upgrade") || !headerContainsToken(req.Header, "Upgrade", "websocket") {
+		return fail(http.StatusBadRequest, "not a websocket upgrade request")
+	}
+	if req.Header.Get("Sec-WebSocket-Version") != "13" {
+		c.SetHeader("Sec-WebSocket-Version", "13")
+		return fail(http.StatusUpgradeRequired, "unsupported websocket version")
+	}
+	key := req.Header.Get("Sec-WebSocket-Key")
+	if decoded, err := base64.StdEncoding.DecodeString(key); err != nil || len(decoded) != 16 {
+		return fail(http.StatusBadRequest, "invalid Sec-WebSocket-Key")
+	}
+	if !opts.CheckOrigin(req) {
+		return fail(http.StatusForbidden, "origin not allowed")
+	}
+	subprotocol := selectSubprotocol(req, opts.Subprotocols)
+
+	netConn, brw, err := c.Writer.Hijack()
+	if err != nil {
+		return fail(http.StatusInternalServerError, err.Error())
+	}
+	// 清除http服务设置的超时，由调用方通过SetReadDeadline等控制
+	netConn.SetDeadline(time.Time{})
+
+	var sb strings.Builder
+	sb.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n")
+	sb.WriteString("Sec-WebSocket-Accept: " + acceptKey(key) + "\r\n")
+	if subprotocol != "" {
+		sb.WriteString("Sec-WebSocket-Protocol: " + subprotocol + "\r\n")
+	}
+	sb.WriteString("\r\n")
+	if _, err = netConn.Write([]byte(sb.String())); err != nil {
+		netConn.Close()
+		return nil, err
+	}
+	c.StatusCode = http.StatusSwitchingProtocols
+
+	return &Conn{
+		conn:        netConn,
+		br:          brw.Reader,
+		readLimit:   opts.ReadLimit,
+		subprotocol: subprotocol,
+	}, nil
+}
+
+// Conn websocket连接，同一时间只能有一个goroutine读取，写入可以并发
+type Conn struct {
+	conn        net.Conn
+	br          *bufio.Reader
+	readLimit   int64
+	subprotocol string
+
+	pingHandler func(data string) error
+	pongHandler func(data string) error
+
+	writeMu   sync.Mutex
+	closeSent bool
+}
+
+// ReadMessage 读取一条完整的消息，分片的消息会被合并，期间收到的ping会自动回复pong，
+// 收到关闭帧时回复关闭帧并返回*CloseError
+func (conn *Conn) ReadMessage() (messageType int, data []byte, err error) {
+	var message []byte
+	for {
+		fin, opcode, payload, err := conn.readFrame(int64(len(message)))
+		if err != nil {
+			return 0, nil, err
+		}
+
+		switch opcode {
+		case PingMessage:
+			if conn.pingHandler != nil {
+				err = conn.pingHandler(string(payload))
+			} else {
+				err = conn.WriteControl(PongMessage, payload)
+			}
+			if err != nil && err != ErrCloseSent {
+				return 0, nil, err
+			}
+			continue
+		case PongMessage:
+			if conn.pongHandler != nil {
+				if err = conn.pongHandler(string(payload)); err != nil {
+					return 0, nil, err
+				}
+			}
+			continue
+		case CloseMessage:
+			return 0, nil, conn.handleClose(payload)
+		case TextMessage, BinaryMessage:
+			if messageType != 0 {
+				return 0, nil, conn.fail(CloseProtocolError, "expected continuation frame")
+			}
+			messageType = opcode
+		case continuationFrame:
+			if messageType == 0 {
+				return 0, nil, conn.fail(CloseProtocolError, "unexpected continuation frame")
+			}
+		default:
+			return 0, nil, conn.fail(CloseProtocolError, fmt.Sprintf("unknown opcode %d", opcode))
+		}
+
+		message = append(message, payload...)
+		if fin {
+			if messageType == TextMessage && !utf8.Valid(message) {
+				return 0, nil, conn.fail(CloseInvalidFramePayloadData, "invalid utf-8 in text message")
+			}
+			return messageType, message, nil
+		}
+	}
+}
+
+// readFrame 读取一帧，read为当前消息已读取的字节数，用于检查消息大小
+func (conn *Conn) readFrame(read int64) (fin bool, opcode int, payload []byte, err error) {
+	var header [2]byte
+	if _, err = io.ReadFull(conn.br, header[:]); err != nil {
+		return false, 0, nil, conn.abnormal(err)
+	}
+	fin = header[0]&0x80 != 0
+	opcode = int(header[0] & 0x0f)
+	if header[0]&0x70 != 0 {
+		return false, 0, nil, conn.fail(CloseProtocolError, "reserved bits must be 0")
+	}
+	// 客户端发送的帧必须使用掩码
+	if header[1]&0x80 == 0 {
+		return false, 0, nil, conn.fail(CloseProtocolError, "client frame is not masked")
+	}
+
+	length := int64(header[1] & 0x7f)
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err = io.ReadFull(conn.br, ext[:]); err != nil {
+			return false, 0, nil, conn.abnormal(err)
+		}
+		length = int64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err = io.ReadFull(conn.br, ext[:]); err != nil {
+			return false, 0, nil, conn.abnormal(err)
+		}
+		if ext[0]&0x80 != 0 {
+			return false, 0, nil, conn.fail(CloseProtocolError, "invalid payload length")
+		}
+		length = int64(binary.BigEndian.Uint64(ext[:]))
+	}
+
+	if opcode >= CloseMessage {
+		if !fin || length > 125 {
+			return false, 0, nil, conn.fail(CloseProtocolError, "invalid control frame")
+		}
+	} else if limit := conn.maxMessageSize(); length > limit-read {
+		// 使用减法比较，read+length可能溢出
+		return false, 0, nil, conn.fail(CloseMessageTooBig, fmt.Sprintf("message exceeds %d bytes", limit))
+	}
+
+	var mask [4]byte
+	if _, err = io.ReadFull(conn.br, mask[:]); err != nil {
+		return false, 0, nil, conn.abnormal(err)
+	}
+	payload = make([]byte, length)
+	if _, err = io.ReadFull(conn.br, payload); err != nil {
+		return false, 0, nil, conn.abnormal(err)
+	}
+	for i := range payload {
+		payload[i] ^= mask[i&3]
+	}
+	return fin, opcode, payload, nil
+}
+
+// handleClose 回复对方的关闭帧并关闭连接
+func (conn *Conn) handleClose(payload []byte) error {
+	closeErr := &CloseError{Code: CloseNoStatusReceived}
+	switch {
+	case len(payload) == 1:
+		return conn.fail(CloseProtocolError, "invalid close frame")
+	case len(payload) >= 2:
+		closeErr.Code = int(binary.BigEndian.Uint16(payload))
+		closeErr.Text = string(payload[2:])
+		if !validCloseCode(closeErr.Code) {
+			return conn.fail(CloseProtocolError, "invalid close code")
+		}
+		if !utf8.ValidString(closeErr.Text) {
+			return conn.fail(CloseInvalidFramePayloadData, "invalid utf-8 in close reason")
+		}
+	}
+	reply := CloseNormalClosure
+	if closeErr.Code != CloseNoStatusReceived {
+		reply = closeErr.Code
+	}
+	conn.WriteClose(reply, "")
+	conn.conn.Close()
+	return closeErr
+}
+
+// fail 发送关闭帧后关闭连接，用于协议错误及消息过大等情况
+func (conn *Conn) fail(code int, text string) error {
+	conn.WriteClose(code, text)
+	conn.conn.Close()
+	return &CloseError{Code: code, Text: text}
+}
+
+// abnormal 连接未经关闭握手而断开
+func (conn *Conn) abnormal(err error) error {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return &CloseError{Code: CloseAbnormalClosure, Text: err.Error()}
+	}
+	return err
+}
+
+// WriteMessage 发送一条消息，messageType为TextMessage或BinaryMessage
+func (conn *Conn) WriteMessage(messageType int, data []byte) error {
+	if messageType != TextMessage && messageType != BinaryMessage {
+		return fmt.Errorf("giga: invalid websocket message type %d", messageType)
+	}
+	return conn.writeFrame(messageType, data)
+}
+
+// WriteControl 发送ping、pong控制帧，数据不能超过125字节
+func (conn *Conn) WriteControl(messageType int, data []byte) error {
+	if messageType != PingMessage && messageType != PongMessage {
+		return fmt.Errorf("giga: invalid websocket control type %d", messageType)
+	}
+	if len(data) > 125 {
+		return errors.New("giga: websocket control frame too large")
+	}
+	return conn.writeFrame(messageType, data)
+}
+
+// Ping 发送ping，对方回复的pong由SetPongHandler设置的函数处理
+func (conn *Conn) Ping(data []byte) error {
+	return conn.WriteControl(PingMessage, data)
+}
+
+// WriteClose 发送关闭帧，之后不能再发送消息，对方回复关闭帧后ReadMessage返回*CloseError
+func (conn *Conn) WriteClose(code int, text string) error {
+	payload := make([]byte, 2, 2+len(text))
+	binary.BigEndian.PutUint16(payload, uint16(code))
+	payload = append(payload, text...)
+	if len(payload) > 125 {
+		payload = payload[:125]
+	}
+	return conn.writeFrame(CloseMessage, payload)
+}
+
+// WriteJSON 将v编码为json并以文本消息发送
+func (conn *Conn) WriteJSON(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return conn.WriteMessage(TextMessage, data)
+}
+
+// ReadJSON 读取一条消息并解码到v
+func (conn *Conn) ReadJSON(v interface{}) error {
+	_, data, err := conn.ReadMessage()
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// writeFrame 服务端发送的帧不使用掩码，消息不分片
+func (conn *Conn) writeFrame(opcode int, payload []byte) error {
+	conn.writeMu.Lock()
+	defer conn.writeMu.Unlock()
+	if conn.closeSent {
+		return ErrCloseSent
+	}
+	if opcode == CloseMessage {
+		conn.closeSent = true
+	}
+
+	frame := make([]byte, 0, 10+len(payload))
+	frame = append(frame, 0x80|byte(opcode))
+	switch n := len(payload); {
+	case n <= 125:
+		frame = append(frame, byte(n))
+	case n <= 0xffff:
+		frame = append(frame, 126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(n))
+	default:
+		frame = append(frame, 127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(n))
+	}
+	frame = append(frame, payload...)
+	_, err := conn.conn.Write(frame)
+	return err
+}
+
+// Close 发送关闭码1000(已发送过关闭帧时跳过)并关闭底层连接
+func (conn *Conn) Close() error {
+	conn.WriteClose(CloseNormalClosure, "")
+	return conn.conn.Close()
+}
+
+// SetReadLimit 设置单条消息的最大字节数，小于等于0时不限制，但仍不能超过1GiB
+func (conn *Conn) SetReadLimit(limit int64) {
+	conn.readLimit = limit
+}
+
+// maxMessageSize 返回单条消息实际允许的最大字节数
+func (conn *Conn) maxMessageSize() int64 {
+	if conn.readLimit <= 0 || conn.readLimit > maxWebSocketReadLimit {
+		return maxWebSocketReadLimit
+	}
+	return conn.readLimit
+}
+
+// SetPingHandler 设置收到ping时的处理函数，默认回复pong，设置后需自行调用WriteControl回复
+func (conn *Conn) SetPingHandler(handler func(data string) error) {
+	conn.pingHandler = handler
+}
+
+// SetPongHandler 设置收到pong时的处理函数，例如用于延长读超时，返回error时ReadMessage返回该error
+func (conn *Conn) SetPongHandler(handler func(data string) error) {
+	conn.pongHandler = handler
+}
+
+func (conn *Conn) SetReadDeadline(t time.Time) error {
+	return conn.conn.SetReadDeadline(t)
+}
+
+func (conn *Conn) SetWriteDeadline(t time.Time) error {
+	return conn.conn.SetWriteDeadline(t)
+}
+
+// Subprotocol 握手时选中的子协议
+func (conn *Conn) Subprotocol() string {
+	return conn.subprotocol
+}
+
+func (conn *Conn) RemoteAddr() net.Addr {
+	return conn.conn.RemoteAddr()
+}
+
+func (conn *Conn) LocalAddr() net.Addr {
+	return conn.conn.LocalAddr()
+}
+
+// acceptKey 计算握手响应中的Sec-WebSocket-Accept
+func acceptKey(key string) string {
+	sum := sha1.Sum([]byte(key + websocketGUID))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// sameOrigin 没有Origin或Origin的host与请求的Host相同
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(u.Host, r.Host)
+}
+
+// headerContainsToken 判断以逗号分隔的请求头中是否包含token，不区分大小写
+func headerContainsToken(header http.Header, name string, token string) bool {
+	for _, value := range header.Values(name) {
+		for _, part := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(part), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func selectSubprotocol(r *http.Request, supported []string) string {
+	for _, protocol := range supported {
+		if headerContainsToken(r.Header, "Sec-WebSocket-Protocol", protocol) {
+			return protocol
+		}
+	}
+	return ""
+}
+
+// validCloseCode 关闭帧中允许出现的关闭码
+func validCloseCode(code int) bool {
+	switch {
+	case code >= 1000 && code <= 1003, code >= 1007 && code <= 1011, code >= 3000 && code <= 4999:
+		return true
+	}
+	return false
+}
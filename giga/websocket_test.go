@@ -0,0 +1,257 @@
+package giga
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// wsClient 测试使用的最小websocket客户端，发送的帧使用掩码
+type wsClient struct {
+	conn net.Conn
+	br   *bufio.Reader
+}
+
+func dialWebSocket(t *testing.T, srv *httptest.Server, path string, header string) (*wsClient, string) {
+	t.Helper()
+	conn, err := net.Dial("tcp", strings.TrimPrefix(srv.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	t.Cleanup(func() { conn.Close() })
+	req := "GET " + path + " HTTP/1.1\r\nHost: " + strings.TrimPrefix(srv.URL, "http://") + "\r\n" +
+		"Upgrade: websocket\r\nConnection: keep-alive, Upgrade\r\nSec-WebSocket-Version: 13\r\n" +
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\n" + header + "\r\n"
+	if _, err = conn.Write([]byte(req)); err != nil {
+		t.Fatal(err)
+	}
+	br := bufio.NewReader(conn)
+	var sb strings.Builder
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		if line == "\r\n" {
+			break
+		}
+		sb.WriteString(line)
+	}
+	return &wsClient{conn, br}, sb.String()
+}
+
+func (ws *wsClient) writeFrame(t *testing.T, fin bool, opcode int, payload []byte) {
+	t.Helper()
+	b0 := byte(opcode)
+	if fin {
+		b0 |= 0x80
+	}
+	frame := []byte{b0}
+	switch n := len(payload); {
+	case n <= 125:
+		frame = append(frame, 0x80|byte(n))
+	case n <= 0xffff:
+		frame = append(frame, 0x80|126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(n))
+	default:
+		frame = append(frame, 0x80|127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(n))
+	}
+	mask := [4]byte{1, 2, 3, 4}
+	frame = append(frame, mask[:]...)
+	for i, b := range payload {
+		frame = append(frame, b^mask[i&3])
+	}
+	if _, err := ws.conn.Write(frame); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func (ws *wsClient) readFrame(t *testing.T) (opcode int, payload []byte) {
+	t.Helper()
+	var header [2]byte
+	if _, err := io.ReadFull(ws.br, header[:]); err != nil {
+		t.Fatal(err)
+	}
+	length := int(header[1] & 0x7f)
+	if length == 126 {
+		var ext [2]byte
+		io.ReadFull(ws.br, ext[:])
+		length = int(binary.BigEndian.Uint16(ext[:]))
+	}
+	payload = make([]byte, length)
+	if _, err := io.ReadFull(ws.br, payload); err != nil {
+		t.Fatal(err)
+	}
+	return int(header[0] & 0x0f), payload
+}
+
+func closeCode(payload []byte) int {
+	if len(payload) < 2 {
+		return 0
+	}
+	return int(binary.BigEndian.Uint16(payload))
+}
+
+func newWebSocketServer(t *testing.T, readErr chan<- error) *httptest.Server {
+	r := NewEngine()
+	auth := func(c *Context) {
+		if c.Query("token") != "secret" {
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+		c.Next()
+	}
+	r.GET("/ws", auth, func(c *Context) {
+		conn, err := c.Upgrade(UpgradeOptions{ReadLimit: 1024, Subprotocols: []string{"chat"}})
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			messageType, data, err := conn.ReadMessage()
+			if err != nil {
+				readErr <- err
+				return
+			}
+			conn.WriteMessage(messageType, data)
+		}
+	})
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestWebSocketHandshake(t *testing.T) {
+	srv := newWebSocketServer(t, make(chan error, 1))
+
+	_, head := dialWebSocket(t, srv, "/ws", "")
+	if !strings.HasPrefix(head, "HTTP/1.1 401") {
+		t.Fatalf("middleware should reject before upgrade, got %q", head)
+	}
+	_, head = dialWebSocket(t, srv, "/ws?token=secret", "Origin: http://evil.example.com\r\n")
+	if !strings.HasPrefix(head, "HTTP/1.1 403") {
+		t.Fatalf("cross origin request should be rejected, got %q", head)
+	}
+	_, head = dialWebSocket(t, srv, "/ws?token=secret", "Sec-WebSocket-Protocol: json, chat\r\n")
+	if !strings.HasPrefix(head, "HTTP/1.1 101") ||
+		!strings.Contains(head, "Sec-WebSocket-Accept: s3pPLMBiTxaQ9kYGzzhZRbK+xOo=\r\n") ||
+		!strings.Contains(head, "Sec-WebSocket-Protocol: chat\r\n") {
+		t.Fatalf("unexpected handshake response %q", head)
+	}
+
+	r := NewEngine()
+	r.GET("/ws", func(c *Context) { c.Upgrade() })
+	req := httptest.NewRequest("GET", "/ws", nil)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Version", "8")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusUpgradeRequired || w.Header().Get("Sec-WebSocket-Version") != "13" {
+		t.Fatalf("unexpected code %d", w.Code)
+	}
+	if w = performRequest(r, "GET", "/ws"); w.Code != http.StatusBadRequest {
+		t.Fatalf("unexpected code %d", w.Code)
+	}
+}
+
+func TestWebSocketMessages(t *testing.T) {
+	readErr := make(chan error, 1)
+	srv := newWebSocketServer(t, readErr)
+	ws, _ := dialWebSocket(t, srv, "/ws?token=secret", "")
+
+	ws.writeFrame(t, true, TextMessage, []byte("hello"))
+	if opcode, payload := ws.readFrame(t); opcode != TextMessage || string(payload) != "hello" {
+		t.Fatalf("unexpected echo %d %q", opcode, payload)
+	}
+
+	// 分片消息中间插入ping，先收到pong再收到合并后的消息
+	ws.writeFrame(t, false, BinaryMessage, []byte("ab"))
+	ws.writeFrame(t, true, PingMessage, []byte("p"))
+	ws.writeFrame(t, true, continuationFrame, []byte("cd"))
+	if opcode, payload := ws.readFrame(t); opcode != PongMessage || string(payload) != "p" {
+		t.Fatalf("unexpected pong %d %q", opcode, payload)
+	}
+	if opcode, payload := ws.readFrame(t); opcode != BinaryMessage || string(payload) != "abcd" {
+		t.Fatalf("unexpected echo %d %q", opcode, payload)
+	}
+
+	// 关闭握手，服务端回复相同的关闭码
+	ws.writeFrame(t, true, CloseMessage, []byte{0x03, 0xe9, 'b', 'y', 'e'})
+	if opcode, payload := ws.readFrame(t); opcode != CloseMessage || closeCode(payload) != CloseGoingAway {
+		t.Fatalf("unexpected close %d %v", opcode, payload)
+	}
+	if err := <-readErr; !IsCloseError(err, CloseGoingAway) {
+		t.Fatalf("unexpected read error %v", err)
+	}
+}
+
+func TestWebSocketProtocolErrors(t *testing.T) {
+	readErr := make(chan error, 1)
+	srv := newWebSocketServer(t, readErr)
+
+	// 消息超过ReadLimit
+	ws, _ := dialWebSocket(t, srv, "/ws?token=secret", "")
+	ws.writeFrame(t, true, BinaryMessage, make([]byte, 2048))
+	if opcode, payload := ws.readFrame(t); opcode != CloseMessage || closeCode(payload) != CloseMessageTooBig {
+		t.Fatalf("unexpected close %d %v", opcode, payload)
+	}
+	if err := <-readErr; !IsCloseError(err, CloseMessageTooBig) {
+		t.Fatalf("unexpected read error %v", err)
+	}
+
+	// 分片消息的后续帧声明超大长度，已读取的长度加上该长度会溢出
+	ws, _ = dialWebSocket(t, srv, "/ws?token=secret", "")
+	ws.writeFrame(t, false, TextMessage, []byte("a"))
+	frame := []byte{0x80 | continuationFrame, 0x80 | 127}
+	frame = binary.BigEndian.AppendUint64(frame, 1<<63-1)
+	ws.conn.Write(append(frame, 1, 2, 3, 4))
+	if opcode, payload := ws.readFrame(t); opcode != CloseMessage || closeCode(payload) != CloseMessageTooBig {
+		t.Fatalf("unexpected close %d %v", opcode, payload)
+	}
+	if err := <-readErr; !IsCloseError(err, CloseMessageTooBig) {
+		t.Fatalf("unexpected read error %v", err)
+	}
+
+	// 未使用掩码的帧
+	ws, _ = dialWebSocket(t, srv, "/ws?token=secret", "")
+	ws.conn.Write([]byte{0x81, 0x01, 'a'})
+	if opcode, payload := ws.readFrame(t); opcode != CloseMessage || closeCode(payload) != CloseProtocolError {
+		t.Fatalf("unexpected close %d %v", opcode, payload)
+	}
+	<-readErr
+
+	// 文本消息不是合法的utf-8
+	ws, _ = dialWebSocket(t, srv, "/ws?token=secret", "")
+	ws.writeFrame(t, true, TextMessage, []byte{0xff, 0xfe})
+	if opcode, payload := ws.readFrame(t); opcode != CloseMessage || closeCode(payload) != CloseInvalidFramePayloadData {
+		t.Fatalf("unexpected close %d %v", opcode, payload)
+	}
+	<-readErr
+}
+
+func TestWebSocketUnlimitedReadLimit(t *testing.T) {
+	server, client := net.Pipe()
+	defer client.Close()
+	conn := &Conn{conn: server, br: bufio.NewReader(server)}
+	conn.SetReadLimit(0)
+
+	go func() {
+		frame := []byte{0x80 | BinaryMessage, 0x80 | 127}
+		frame = binary.BigEndian.AppendUint64(frame, 1<<62)
+		client.Write(append(frame, 1, 2, 3, 4))
+		// 读取服务端发送的关闭帧
+		io.Copy(io.Discard, client)
+	}()
+	if _, _, err := conn.ReadMessage(); !IsCloseError(err, CloseMessageTooBig) {
+		t.Fatalf("unexpected read error %v", err)
+	}
+}
@@ -0,0 +1,42 @@
+package giga
+
+import (
+	"net/http"
+	"strings"
+)
+
+// mountParam Mount时用于转发剩余路径的通配参数名
+const mountParam = "giga_mount_path"
+
+// WrapF 将标准库的http.HandlerFunc转换为giga的HandlerFunc
+func WrapF(f http.HandlerFunc) HandlerFunc {
+	return func(c *Context) {
+		f(c.Writer, c.Req)
+	}
+}
+
+// WrapH 将标准库的http.Handler转换为giga的HandlerFunc，例如 r.GET("/metrics", giga.WrapH(promhttp.Handler()))
+func WrapH(h http.Handler) HandlerFunc {
+	return func(c *Context) {
+		h.ServeHTTP(c.Writer, c.Req)
+	}
+}
+
+// Mount 将http.Handler挂载到prefix下，所有请求方法的 prefix 及 prefix/* 请求都会转发给handler，
+// 转发时去除prefix，例如挂载到/admin时，/admin/users 转发为 /users。
+// handler可以是单独构建的*Engine，分组的中间件先执行，之后子engine执行自身的中间件
+func (group *RouterGroup) Mount(prefix string, handler http.Handler) {
+	mounted := func(c *Context) {
+		req := new(http.Request)
+		*req = *c.Req
+		u := *c.Req.URL
+		u.Path = "/" + strings.TrimPrefix(c.Param(mountParam), "/")
+		u.RawPath = ""
+		req.URL = &u
+		handler.ServeHTTP(c.Writer, req)
+	}
+
+	prefix = strings.TrimSuffix(prefix, "/")
+	group.Any(prefix, mounted)
+	group.Any(prefix+"/*"+mountParam, mounted)
+}